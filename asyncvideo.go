@@ -0,0 +1,194 @@
+package gondi
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// AsyncVideoSender sends video with SendVideoFrameAsync from a pool of Go owned frame buffers. The SDK keeps using
+// the buffer of an asynchronously sent frame until the next frame is submitted, so a buffer only returns to the pool
+// once the SDK has released it. With two buffers, one frame can be rendered while the other is being sent, three
+// buffers give triple buffering.
+//
+// Frames are acquired with Acquire, filled in, and passed to Submit. It is safe to use from multiple goroutines.
+type AsyncVideoSender struct {
+	sender *SendInstance
+	free   chan *VideoFrameV2
+	done   chan struct{}
+
+	mu       sync.Mutex
+	frames   map[*VideoFrameV2]*asyncVideoBuffer
+	inFlight *VideoFrameV2
+	closed   bool
+	pinner   runtime.Pinner
+}
+
+type asyncVideoBuffer struct {
+	data     []byte
+	acquired bool
+}
+
+// Create an async sender with a pool of buffers for frames in the given format. The resolution, FourCC, frame rate,
+// aspect ratio, frame format and line stride are copied from the format frame, its data is not used. If the line
+// stride is 0, the default stride for the FourCC is used. At least two buffers are needed.
+func (p *SendInstance) NewAsyncVideoSender(format *VideoFrameV2, buffers int) (*AsyncVideoSender, error) {
+	if buffers < 2 {
+		return nil, errors.New("an async video sender needs at least two buffers")
+	}
+
+	stride := format.LineStride
+	if stride == 0 {
+		stride = format.FourCC.LineStride(format.Xres)
+	}
+	if stride == 0 || format.Yres <= 0 {
+		return nil, errors.New("unable to determine the buffer size for the video format")
+	}
+
+	s := &AsyncVideoSender{
+		sender: p,
+		free:   make(chan *VideoFrameV2, buffers),
+		done:   make(chan struct{}),
+		frames: make(map[*VideoFrameV2]*asyncVideoBuffer, buffers),
+	}
+
+	for i := 0; i < buffers; i++ {
+		frame := NewVideoFrameV2()
+		frame.Xres = format.Xres
+		frame.Yres = format.Yres
+		frame.FourCC = format.FourCC
+		frame.FrameRateN = format.FrameRateN
+		frame.FrameRateD = format.FrameRateD
+		frame.PictureAspectRatio = format.PictureAspectRatio
+		frame.FrameFormatType = format.FrameFormatType
+		frame.LineStride = stride
+
+		data := make([]byte, frame.DataSize())
+		frame.Data = &data[0]
+
+		// The SDK reads the buffers after the send call has returned
+		s.pinner.Pin(frame)
+		s.pinner.Pin(frame.Data)
+
+		s.frames[frame] = &asyncVideoBuffer{data: data}
+		s.free <- frame
+	}
+
+	return s, nil
+}
+
+// Get a free frame from the pool, waiting until one is released by the SDK or the context is done.
+// The timecode of the frame is reset to be synthesized, and its metadata is cleared.
+func (s *AsyncVideoSender) Acquire(ctx context.Context) (*VideoFrameV2, error) {
+	select {
+	case frame := <-s.free:
+		return s.prepare(frame)
+	case <-s.done:
+		return nil, ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Get a free frame from the pool without waiting. Returns false if all frames are in use.
+func (s *AsyncVideoSender) TryAcquire() (*VideoFrameV2, bool) {
+	select {
+	case frame := <-s.free:
+		frame, err := s.prepare(frame)
+		return frame, err == nil
+	default:
+		return nil, false
+	}
+}
+
+func (s *AsyncVideoSender) prepare(frame *VideoFrameV2) (*VideoFrameV2, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+
+	s.frames[frame].acquired = true
+	frame.Timecode = SendTimecodeSynthesize
+	frame.Metadata = nil
+	return frame, nil
+}
+
+// Send an acquired frame asynchronously. The previously submitted frame is released by the SDK, and returns to the pool.
+// If sending fails, the frame returns to the pool and the error is returned.
+func (s *AsyncVideoSender) Submit(frame *VideoFrameV2) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	buffer, ok := s.frames[frame]
+	if !ok {
+		return errors.New("frame does not belong to this async video sender")
+	}
+	if !buffer.acquired {
+		return errors.New("frame has not been acquired")
+	}
+	buffer.acquired = false
+
+	if err := s.sender.SendVideoFrameAsync(frame); err != nil {
+		s.free <- frame
+		return err
+	}
+
+	if s.inFlight != nil {
+		s.free <- s.inFlight
+	}
+	s.inFlight = frame
+
+	return nil
+}
+
+// Return an acquired frame to the pool without sending it. Frames that are not acquired are ignored.
+func (s *AsyncVideoSender) Release(frame *VideoFrameV2) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if buffer, ok := s.frames[frame]; ok && buffer.acquired && !s.closed {
+		buffer.acquired = false
+		s.free <- frame
+	}
+}
+
+// Get the data buffer of a frame from the pool, as a byte slice.
+func (s *AsyncVideoSender) Buffer(frame *VideoFrameV2) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if buffer, ok := s.frames[frame]; ok {
+		return buffer.data
+	}
+	return nil
+}
+
+// Flush the frame in flight with SendVideoFrameAsync(nil), and release all buffers. Frames that are still acquired
+// must not be used after this. It is safe to call Close more than once.
+func (s *AsyncVideoSender) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	// A closed sender has already released the frame when it was destroyed
+	err := s.sender.SendVideoFrameAsync(nil)
+	if errors.Is(err, ErrClosed) {
+		err = nil
+	}
+
+	s.inFlight = nil
+	close(s.done)
+	s.pinner.Unpin()
+
+	return err
+}
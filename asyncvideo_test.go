@@ -0,0 +1,82 @@
+package gondi
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAsyncVideoSender(t *testing.T) {
+	// The SDK holds an asynchronously sent frame until the next one is sent
+	var holding *VideoFrameV2
+	stubLibrary(t, &ndilib_send_send_video_async_v2, func(instance uintptr, frame uintptr) {
+		holding = stubArg[VideoFrameV2](frame)
+	})
+
+	format := NewVideoFrameV2()
+	format.Xres, format.Yres = 64, 36
+	format.FourCC = FourCCTypeUYVY
+
+	sender := &SendInstance{}
+	s, err := sender.NewAsyncVideoSender(format, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.LineStride != 128 || len(s.Buffer(first)) != 128*36 {
+		t.Errorf("frame has a stride of %d and %d bytes", first.LineStride, len(s.Buffer(first)))
+	}
+	first.FillBlack()
+	if err := s.Submit(first); err != nil {
+		t.Fatal(err)
+	}
+
+	second, ok := s.TryAcquire()
+	if !ok || second == first {
+		t.Fatal("did not get the second buffer while the first is in flight")
+	}
+
+	// The SDK still holds the first frame, so the pool is empty
+	if _, ok := s.TryAcquire(); ok {
+		t.Fatal("got a buffer that is still held by the SDK")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx); err == nil {
+		t.Fatal("Acquire did not time out with an empty pool")
+	}
+
+	// Submitting the second frame releases the first
+	s.Submit(second)
+	if holding != second {
+		t.Error("the SDK does not hold the second frame")
+	}
+	again, ok := s.TryAcquire()
+	if !ok || again != first {
+		t.Fatal("the first buffer did not return to the pool")
+	}
+	s.Release(again)
+	s.Release(again)
+	if err := s.Submit(again); err == nil {
+		t.Error("submitting a frame that was not acquired did not fail")
+	}
+
+	if err := s.Submit(NewVideoFrameV2()); err == nil {
+		t.Error("submitting a frame from outside the pool did not fail")
+	}
+
+	s.Close()
+	if holding != nil || sender.GetPerformance().VideoFrames != 2 {
+		t.Errorf("close did not flush the frame in flight, %d frames sent", sender.GetPerformance().VideoFrames)
+	}
+	if _, err := s.Acquire(context.Background()); err != ErrClosed {
+		t.Errorf("Acquire after close returned %v, want ErrClosed", err)
+	}
+	if err := s.Submit(first); err != ErrClosed {
+		t.Errorf("Submit after close returned %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Package audio provides pure Go audio processing for gondi audio frames: level metering and loudness
+measurement, sample rate conversion and channel routing.
+
+All types in this package work on planar float32 frames as used by gondi.AudioFrameV2, and they do
+not need the NDI library to be initialized.
+*/
+package audio
+
+import (
+	"math"
+	"unsafe"
+
+	"github.com/bitfocus/gondi"
+)
+
+// Get the samples of one channel of a planar audio frame. ChannelStride is honoured if it is set,
+// otherwise the channels are assumed to follow each other without any padding.
+func channel(frame *gondi.AudioFrameV2, ch int) []float32 {
+	if frame.Data == nil || frame.NumSamples <= 0 {
+		return nil
+	}
+	stride := int(frame.ChannelStride) / 4
+	if stride == 0 {
+		stride = int(frame.NumSamples)
+	}
+	all := unsafe.Slice(frame.Data, stride*(int(frame.NumChannels)-1)+int(frame.NumSamples))
+
+	return all[ch*stride : ch*stride+int(frame.NumSamples)]
+}
+
+// Convert a linear amplitude to dB, silence is returned as -Inf.
+func toDB(v float64) float64 {
+	if v <= 0 {
+		return math.Inf(-1)
+	}
+	return 20 * math.Log10(v)
+}
+
+// Convert a dB value to a linear amplitude, -Inf is returned as 0.
+func fromDB(db float64) float64 {
+	if math.IsInf(db, -1) {
+		return 0
+	}
+	return math.Pow(10, db/20)
+}
+
+// Normalized sinc function, sin(pi*x)/(pi*x).
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// Kaiser window of the given beta, evaluated at x in the range -1 to 1.
+func kaiser(x float64, beta float64) float64 {
+	if x < -1 || x > 1 {
+		return 0
+	}
+	return besselI0(beta*math.Sqrt(1-x*x)) / besselI0(beta)
+}
+
+// Zeroth order modified bessel function of the first kind.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
+
+// A direct form I biquad filter section.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
@@ -0,0 +1,169 @@
+package audio
+
+import "math"
+
+const (
+	// Loudness is measured in 100ms sub-blocks, and gated over 400ms blocks with 75% overlap.
+	loudnessSubBlocks = 30
+	momentaryBlocks   = 4
+
+	// Integrated loudness is kept as a histogram, so it does not grow with the length of the measurement.
+	histogramMin  = -70.0
+	histogramStep = 0.1
+	histogramBins = 800
+)
+
+// Loudness measurement as specified by ITU-R BS.1770-4 and EBU R128.
+type loudness struct {
+	blockSize int
+	filled    int
+	energy    []float64
+
+	subBlocks [loudnessSubBlocks]float64
+	count     int
+	next      int
+
+	histogramCount  [histogramBins]uint64
+	histogramEnergy [histogramBins]float64
+}
+
+// Get the K-weighting pre-filter for the given sample rate.
+// The coefficients are derived for any sample rate as done by libebur128, and match BS.1770 at 48kHz.
+func kWeighting(fs float64) [2]biquad {
+	var f [2]biquad
+
+	// High shelf modelling the acoustic effect of the head
+	f0, gain, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / fs)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	f[0] = biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	// High pass (RLB weighting)
+	f0, q = 38.13547087602444, 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / fs)
+	a0 = 1 + k/q + k*k
+	f[1] = biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	return f
+}
+
+// Convert a mean square energy to LUFS.
+func energyToLUFS(e float64) float64 {
+	if e <= 0 {
+		return math.Inf(-1)
+	}
+	return -0.691 + 10*math.Log10(e)
+}
+
+func (l *loudness) setup(sampleRate int32, numChannels int) {
+	l.blockSize = int(math.Round(float64(sampleRate) / 10))
+	l.filled = 0
+	l.energy = make([]float64, numChannels)
+	l.count = 0
+	l.next = 0
+	l.resetIntegrated()
+}
+
+func (l *loudness) resetIntegrated() {
+	l.histogramCount = [histogramBins]uint64{}
+	l.histogramEnergy = [histogramBins]float64{}
+}
+
+// Finish a 100ms sub-block, and feed a 400ms gating block to the integrated loudness.
+func (l *loudness) endBlock(channels []channelMeter) {
+	var z float64
+	for ch := range l.energy {
+		z += channels[ch].weight * l.energy[ch] / float64(l.blockSize)
+		l.energy[ch] = 0
+	}
+	l.filled = 0
+
+	l.subBlocks[l.next] = z
+	l.next = (l.next + 1) % loudnessSubBlocks
+	if l.count < loudnessSubBlocks {
+		l.count++
+	}
+
+	if l.count >= momentaryBlocks {
+		e := l.mean(momentaryBlocks)
+		if lufs := energyToLUFS(e); lufs >= histogramMin {
+			bin := int((lufs - histogramMin) / histogramStep)
+			if bin >= histogramBins {
+				bin = histogramBins - 1
+			}
+			l.histogramCount[bin]++
+			l.histogramEnergy[bin] += e
+		}
+	}
+}
+
+// Mean energy of the last n sub-blocks
+func (l *loudness) mean(n int) float64 {
+	var sum float64
+	i := l.next
+	for k := 0; k < n; k++ {
+		i--
+		if i < 0 {
+			i = loudnessSubBlocks - 1
+		}
+		sum += l.subBlocks[i]
+	}
+	return sum / float64(n)
+}
+
+func (l *loudness) momentary() float64 {
+	if l.count < momentaryBlocks {
+		return math.Inf(-1)
+	}
+	return energyToLUFS(l.mean(momentaryBlocks))
+}
+
+func (l *loudness) shortTerm() float64 {
+	if l.count < loudnessSubBlocks {
+		return math.Inf(-1)
+	}
+	return energyToLUFS(l.mean(loudnessSubBlocks))
+}
+
+// Integrated loudness using the absolute gate of -70 LUFS, and the relative gate of -10 LU.
+func (l *loudness) integrated() float64 {
+	var count uint64
+	var energy float64
+	for i := range l.histogramCount {
+		count += l.histogramCount[i]
+		energy += l.histogramEnergy[i]
+	}
+	if count == 0 {
+		return math.Inf(-1)
+	}
+
+	threshold := energyToLUFS(energy/float64(count)) - 10
+
+	count, energy = 0, 0
+	for i := range l.histogramCount {
+		if histogramMin+(float64(i)+0.5)*histogramStep < threshold {
+			continue
+		}
+		count += l.histogramCount[i]
+		energy += l.histogramEnergy[i]
+	}
+	if count == 0 {
+		return math.Inf(-1)
+	}
+
+	return energyToLUFS(energy / float64(count))
+}
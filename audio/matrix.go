@@ -0,0 +1,281 @@
+package audio
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bitfocus/gondi"
+)
+
+// A Preset is a set of crosspoint gains in dB, indexed by output and then by input.
+// Crosspoints that are missing from the preset, or set to -Inf, are turned off.
+type Preset [][]float64
+
+// A Matrix routes and mixes the channels of planar audio frames. Every output channel is the sum of
+// all input channels, each multiplied by the gain of its crosspoint.
+//
+// Gain and mute changes can be made from any goroutine while audio is flowing. They are applied as
+// a linear ramp over the ramp time, to avoid clicks. Process does not allocate, so it is suitable
+// for the audio path.
+type Matrix struct {
+	inputs, outputs int
+
+	mu          sync.Mutex
+	gains       []float64
+	muted       []bool
+	outputMuted []bool
+	ramp        time.Duration
+	version     uint64
+
+	// Owned by the goroutine calling Process
+	seen      uint64
+	goal      []float64
+	current   []float64
+	delta     []float64
+	remaining int
+}
+
+// Create a matrix with the given number of input and output channels, with all crosspoints turned off.
+func NewMatrix(inputs int, outputs int) *Matrix {
+	if inputs <= 0 || outputs <= 0 {
+		panic("a matrix needs at least one input and one output")
+	}
+
+	m := &Matrix{
+		inputs:      inputs,
+		outputs:     outputs,
+		gains:       make([]float64, inputs*outputs),
+		muted:       make([]bool, inputs*outputs),
+		outputMuted: make([]bool, outputs),
+		ramp:        10 * time.Millisecond,
+		goal:        make([]float64, inputs*outputs),
+		current:     make([]float64, inputs*outputs),
+		delta:       make([]float64, inputs*outputs),
+	}
+	for i := range m.gains {
+		m.gains[i] = math.Inf(-1)
+	}
+
+	return m
+}
+
+// Get the number of input channels of the matrix.
+func (m *Matrix) Inputs() int {
+	return m.inputs
+}
+
+// Get the number of output channels of the matrix.
+func (m *Matrix) Outputs() int {
+	return m.outputs
+}
+
+// Set the duration of the gain ramp used when a crosspoint changes. Default is 10ms, 0 applies changes immediately.
+func (m *Matrix) SetRampTime(ramp time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ramp = ramp
+}
+
+// Set the gain of a crosspoint in dB. Use math.Inf(-1) to turn the crosspoint off.
+func (m *Matrix) SetGain(input int, output int, db float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.gains[m.index(input, output)] = db
+	m.version++
+}
+
+// Get the gain of a crosspoint in dB, and whether it is muted.
+func (m *Matrix) Gain(input int, output int) (db float64, muted bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.index(input, output)
+	return m.gains[i], m.muted[i]
+}
+
+// Mute or unmute a crosspoint, keeping its gain.
+func (m *Matrix) SetMute(input int, output int, muted bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.muted[m.index(input, output)] = muted
+	m.version++
+}
+
+// Mute or unmute a whole output channel.
+func (m *Matrix) SetOutputMute(output int, muted bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.index(0, output)
+	m.outputMuted[output] = muted
+	m.version++
+}
+
+// Turn all crosspoints off, and clear all mutes.
+func (m *Matrix) Clear() {
+	m.LoadPreset(nil)
+}
+
+// Replace all crosspoints with the gains of a preset, and clear all mutes.
+// Crosspoints of the preset that are outside of the matrix are ignored.
+func (m *Matrix) LoadPreset(preset Preset) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.gains {
+		m.gains[i] = math.Inf(-1)
+		m.muted[i] = false
+	}
+	for o := range m.outputMuted {
+		m.outputMuted[o] = false
+	}
+	for o, row := range preset {
+		for i, db := range row {
+			if o < m.outputs && i < m.inputs {
+				m.gains[o*m.inputs+i] = db
+			}
+		}
+	}
+	m.version++
+}
+
+// Mix an input frame into an output frame. The Data of the output frame must have room for
+// Outputs() * in.NumSamples samples, for instance by using gondi.NewAudioFrameV2Preallocated.
+// The format fields of the output frame are set to match the input frame.
+// Matrix inputs above the number of channels in the input frame are silent.
+func (m *Matrix) Process(in *gondi.AudioFrameV2, out *gondi.AudioFrameV2) error {
+	if in == nil || out == nil || in.Data == nil || out.Data == nil {
+		return errors.New("input and output frames need data")
+	}
+
+	m.mu.Lock()
+	if m.version != m.seen {
+		m.seen = m.version
+		m.startRamp(int(math.Round(m.ramp.Seconds() * float64(in.SampleRate))))
+	}
+	m.mu.Unlock()
+
+	n := int(in.NumSamples)
+	out.SampleRate = in.SampleRate
+	out.NumChannels = int32(m.outputs)
+	out.NumSamples = in.NumSamples
+	out.ChannelStride = in.NumSamples * 4
+	out.Timecode = in.Timecode
+	out.Timestamp = in.Timestamp
+
+	ramp := m.remaining
+	if ramp > n {
+		ramp = n
+	}
+
+	for o := 0; o < m.outputs; o++ {
+		dst := channel(out, o)
+		for k := range dst {
+			dst[k] = 0
+		}
+
+		for i := 0; i < m.inputs && i < int(in.NumChannels); i++ {
+			x := o*m.inputs + i
+			if m.current[x] == 0 && m.goal[x] == 0 {
+				continue
+			}
+
+			src := channel(in, i)
+			g := m.current[x]
+			for k := 0; k < ramp; k++ {
+				g += m.delta[x]
+				dst[k] += src[k] * float32(g)
+			}
+			for k := ramp; k < n; k++ {
+				dst[k] += src[k] * float32(g)
+			}
+		}
+	}
+
+	// Advance the ramp state of all crosspoints, including the ones that had no input
+	m.remaining -= ramp
+	for x := range m.current {
+		if m.remaining == 0 {
+			m.current[x] = m.goal[x]
+		} else {
+			m.current[x] += m.delta[x] * float64(ramp)
+		}
+	}
+
+	return nil
+}
+
+// Copy the configured gains to the audio side, and start ramping towards them. Called with mu held.
+func (m *Matrix) startRamp(samples int) {
+	for o := 0; o < m.outputs; o++ {
+		for i := 0; i < m.inputs; i++ {
+			x := o*m.inputs + i
+			if m.muted[x] || m.outputMuted[o] {
+				m.goal[x] = 0
+			} else {
+				m.goal[x] = fromDB(m.gains[x])
+			}
+		}
+	}
+
+	m.remaining = samples
+	for x := range m.current {
+		if samples > 0 {
+			m.delta[x] = (m.goal[x] - m.current[x]) / float64(samples)
+		} else {
+			m.current[x] = m.goal[x]
+			m.delta[x] = 0
+		}
+	}
+}
+
+func (m *Matrix) index(input int, output int) int {
+	if input < 0 || input >= m.inputs || output < 0 || output >= m.outputs {
+		panic("crosspoint is outside of the matrix")
+	}
+	return output*m.inputs + input
+}
+
+// Get a preset passing count channels straight through, starting at input channel first. For instance,
+// SelectChannels(2, 2) sends the third and fourth input channel to the first and second output.
+func SelectChannels(first int, count int) Preset {
+	p := make(Preset, count)
+	for o := range p {
+		p[o] = make([]float64, first+count)
+		for i := range p[o] {
+			p[o][i] = math.Inf(-1)
+		}
+		p[o][first+o] = 0
+	}
+	return p
+}
+
+// Get a preset passing all channels straight through.
+func Identity(channels int) Preset {
+	return SelectChannels(0, channels)
+}
+
+// Get a preset downmixing 5.1 in the L, R, C, LFE, Ls, Rs channel order to stereo, as specified by ITU-R BS.775.
+// The centre and surround channels are mixed in at -3dB, and LFE is dropped.
+func Downmix51ToStereo() Preset {
+	off := math.Inf(-1)
+	return Preset{
+		{0, off, -3, off, -3, off},
+		{off, 0, -3, off, off, -3},
+	}
+}
+
+// Get a preset downmixing stereo to mono, with both channels mixed at -6dB.
+func DownmixStereoToMono() Preset {
+	return Preset{{-6, -6}}
+}
+
+// Get a preset sending a mono channel to both channels of a stereo output.
+func UpmixMonoToStereo() Preset {
+	return Preset{{0}, {0}}
+}
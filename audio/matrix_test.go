@@ -0,0 +1,111 @@
+package audio
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/bitfocus/gondi"
+)
+
+func constantFrame(levels ...float32) *gondi.AudioFrameV2 {
+	const numSamples = 480
+	data := make([]float32, len(levels)*numSamples)
+	for ch, level := range levels {
+		for i := 0; i < numSamples; i++ {
+			data[ch*numSamples+i] = level
+		}
+	}
+
+	frame := gondi.NewAudioFrameV2()
+	frame.SampleRate = 48000
+	frame.NumChannels = int32(len(levels))
+	frame.NumSamples = numSamples
+	frame.Data = &data[0]
+
+	return frame
+}
+
+func TestMatrixDownmix(t *testing.T) {
+	m := NewMatrix(6, 2)
+	m.SetRampTime(0)
+	m.LoadPreset(Downmix51ToStereo())
+
+	out := gondi.NewAudioFrameV2Preallocated(2, 480)
+	if err := m.Process(constantFrame(0.1, 0.2, 0.3, 0.4, 0.5, 0.6), out); err != nil {
+		t.Fatal(err)
+	}
+
+	g := math.Pow(10, -3.0/20)
+	want := []float64{0.1 + g*0.3 + g*0.5, 0.2 + g*0.3 + g*0.6}
+	for o := range want {
+		if got := float64(channel(out, o)[100]); math.Abs(got-want[o]) > 1e-6 {
+			t.Errorf("output %d is %f, want %f", o, got, want[o])
+		}
+	}
+}
+
+func TestMatrixSelect(t *testing.T) {
+	m := NewMatrix(16, 2)
+	m.SetRampTime(0)
+	m.LoadPreset(SelectChannels(2, 2))
+
+	levels := make([]float32, 16)
+	for i := range levels {
+		levels[i] = float32(i) / 16
+	}
+	out := gondi.NewAudioFrameV2Preallocated(2, 480)
+	m.Process(constantFrame(levels...), out)
+
+	if out.NumChannels != 2 || out.NumSamples != 480 {
+		t.Fatalf("output has %d channels of %d samples", out.NumChannels, out.NumSamples)
+	}
+	if channel(out, 0)[0] != levels[2] || channel(out, 1)[0] != levels[3] {
+		t.Errorf("got %f and %f, want %f and %f", channel(out, 0)[0], channel(out, 1)[0], levels[2], levels[3])
+	}
+}
+
+func TestMatrixRamp(t *testing.T) {
+	m := NewMatrix(1, 1)
+	m.SetGain(0, 0, 0)
+
+	// The default ramp of 10ms takes 480 samples at 48kHz
+	out := gondi.NewAudioFrameV2Preallocated(1, 480)
+	m.Process(constantFrame(1), out)
+
+	samples := channel(out, 0)
+	for i := 1; i < len(samples); i++ {
+		if step := samples[i] - samples[i-1]; step < 0 || step > 1.0/400 {
+			t.Fatalf("step of %f at sample %d is not a smooth ramp", step, i)
+		}
+	}
+	if samples[len(samples)-1] < 0.999 {
+		t.Errorf("ramp ended at %f, want 1", samples[len(samples)-1])
+	}
+
+	m.SetMute(0, 0, true)
+	m.Process(constantFrame(1), out)
+	if last := channel(out, 0)[479]; last > 1e-6 {
+		t.Errorf("muted output is %f, want 0", last)
+	}
+}
+
+func TestMatrixConcurrentChanges(t *testing.T) {
+	m := NewMatrix(2, 2)
+	in := constantFrame(0.5, 0.5)
+	out := gondi.NewAudioFrameV2Preallocated(2, 480)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			m.SetGain(i%2, (i/2)%2, float64(-i%20))
+			m.SetOutputMute(i%2, i%3 == 0)
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		m.Process(in, out)
+	}
+	wg.Wait()
+}
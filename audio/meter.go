@@ -0,0 +1,391 @@
+package audio
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bitfocus/gondi"
+)
+
+// Configuration of a Meter. Zero values are replaced with the documented defaults.
+type MeterConfig struct {
+	// How long a peak is held before it starts to fall back. Default is 1.5 seconds.
+	PeakHold time.Duration
+
+	// How fast the peak and true-peak values fall after the hold time, in dB per second.
+	// Default is 20 dB/s.
+	PeakDecay float64
+
+	// Integration time of the RMS value. Default is 300ms.
+	RMSWindow time.Duration
+
+	// How often a new set of levels is published, measured in audio time. Default is 50ms.
+	Interval time.Duration
+
+	// Loudness weight of each channel, as defined by ITU-R BS.1770. Channels that are not in the
+	// list get a weight of 1.0. Use 0 to exclude a channel such as LFE, or 1.41 for surround channels.
+	ChannelWeights []float64
+
+	// Called with each published set of levels, from the goroutine calling Process.
+	OnLevels func(Levels)
+}
+
+// The levels of a single audio channel. All values are in dBFS, and -Inf for silence.
+type ChannelLevels struct {
+	// The sample peak, including hold and decay.
+	Peak float64
+
+	// The RMS level over the configured RMS window.
+	RMS float64
+
+	// The true-peak (inter-sample peak) level in dBTP, including hold and decay.
+	TruePeak float64
+
+	// The highest sample peak and true-peak seen since the meter was created or reset.
+	MaxPeak, MaxTruePeak float64
+}
+
+// A published set of levels for all channels of a stream.
+type Levels struct {
+	// The timecode of the last frame that was part of this measurement.
+	Timecode int64
+
+	// The sample rate of the measured audio.
+	SampleRate int32
+
+	// The per channel levels.
+	Channels []ChannelLevels
+
+	// EBU R128 momentary (400ms), short-term (3s) and integrated (gated, since reset) loudness in LUFS.
+	// The values are -Inf until enough audio has been measured.
+	Momentary, ShortTerm, Integrated float64
+}
+
+// A Meter measures the peak, RMS, true-peak and loudness of a stream of audio frames.
+// Feed it with Process, and read the results through MeterConfig.OnLevels, Updates or Current.
+// The format of the stream may change at any time, which restarts the measurement.
+type Meter struct {
+	config MeterConfig
+
+	sampleRate int32
+	channels   []channelMeter
+	loudness   loudness
+	samples    [][]float32
+	interval   int
+	pending    int
+	timecode   int64
+
+	mu      sync.Mutex
+	current Levels
+	updates chan Levels
+}
+
+type channelMeter struct {
+	kweighting [2]biquad
+	truePeak   truePeakDetector
+	weight     float64
+	meanSquare float64
+	rmsAlpha   float64
+
+	blockPeak, blockTruePeak float64
+	peak, truePeakHold       ballistic
+	maxPeak, maxTruePeak     float64
+}
+
+// Peak hold and decay state, in dB.
+type ballistic struct {
+	value float64
+	hold  float64
+}
+
+// Create a new meter, the config parameter may be nil to use the defaults.
+func NewMeter(config *MeterConfig) *Meter {
+	m := &Meter{}
+	if config != nil {
+		m.config = *config
+	}
+	if m.config.PeakHold == 0 {
+		m.config.PeakHold = 1500 * time.Millisecond
+	}
+	if m.config.PeakDecay == 0 {
+		m.config.PeakDecay = 20
+	}
+	if m.config.RMSWindow == 0 {
+		m.config.RMSWindow = 300 * time.Millisecond
+	}
+	if m.config.Interval == 0 {
+		m.config.Interval = 50 * time.Millisecond
+	}
+	m.current = silentLevels(0, 0)
+
+	return m
+}
+
+// Measure an audio frame. Levels are published every configured interval of audio.
+// Process is not safe for concurrent use, but Updates and Current may be used from other goroutines.
+func (m *Meter) Process(frame *gondi.AudioFrameV2) {
+	if frame == nil || frame.NumChannels <= 0 || frame.SampleRate <= 0 {
+		return
+	}
+	if frame.SampleRate != m.sampleRate || int(frame.NumChannels) != len(m.channels) {
+		m.setup(frame.SampleRate, int(frame.NumChannels))
+	}
+	m.timecode = frame.Timecode
+
+	samples := m.samples
+	for ch := range samples {
+		samples[ch] = channel(frame, ch)
+	}
+
+	n := int(frame.NumSamples)
+	for pos := 0; pos < n; {
+		end := n
+		if left := m.interval - m.pending; pos+left < end {
+			end = pos + left
+		}
+		if left := m.loudness.blockSize - m.loudness.filled; pos+left < end {
+			end = pos + left
+		}
+
+		for ch := range m.channels {
+			m.channels[ch].process(samples[ch][pos:end], &m.loudness.energy[ch])
+		}
+
+		m.loudness.filled += end - pos
+		if m.loudness.filled == m.loudness.blockSize {
+			m.loudness.endBlock(m.channels)
+		}
+		m.pending += end - pos
+		if m.pending == m.interval {
+			m.publish()
+		}
+		pos = end
+	}
+}
+
+// Get a channel that receives published levels. The channel only holds the latest value, so a slow reader
+// skips intermediate updates instead of blocking the audio path.
+func (m *Meter) Updates() <-chan Levels {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.updates == nil {
+		m.updates = make(chan Levels, 1)
+	}
+	return m.updates
+}
+
+// Get the last published levels.
+func (m *Meter) Current() Levels {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.current
+}
+
+// Reset the maximum peaks and the integrated loudness. Like Process, this must not be called concurrently with Process.
+func (m *Meter) Reset() {
+	for ch := range m.channels {
+		m.channels[ch].maxPeak = 0
+		m.channels[ch].maxTruePeak = 0
+	}
+	m.loudness.resetIntegrated()
+}
+
+func (m *Meter) setup(sampleRate int32, numChannels int) {
+	m.sampleRate = sampleRate
+	m.channels = make([]channelMeter, numChannels)
+	m.samples = make([][]float32, numChannels)
+	m.interval = int(math.Max(1, math.Round(m.config.Interval.Seconds()*float64(sampleRate))))
+	m.pending = 0
+
+	rmsAlpha := 1 - math.Exp(-1/(m.config.RMSWindow.Seconds()*float64(sampleRate)))
+	for ch := range m.channels {
+		c := &m.channels[ch]
+		c.kweighting = kWeighting(float64(sampleRate))
+		c.truePeak = newTruePeakDetector(sampleRate)
+		c.rmsAlpha = rmsAlpha
+		c.weight = 1
+		if ch < len(m.config.ChannelWeights) {
+			c.weight = m.config.ChannelWeights[ch]
+		}
+		c.peak.value = math.Inf(-1)
+		c.truePeakHold.value = math.Inf(-1)
+	}
+	m.loudness.setup(sampleRate, numChannels)
+}
+
+func (c *channelMeter) process(samples []float32, energy *float64) {
+	for _, s := range samples {
+		x := float64(s)
+
+		if a := math.Abs(x); a > c.blockPeak {
+			c.blockPeak = a
+		}
+		if tp := c.truePeak.process(x); tp > c.blockTruePeak {
+			c.blockTruePeak = tp
+		}
+
+		c.meanSquare += (x*x - c.meanSquare) * c.rmsAlpha
+
+		k := c.kweighting[1].process(c.kweighting[0].process(x))
+		*energy += k * k
+	}
+}
+
+func (m *Meter) publish() {
+	elapsed := float64(m.pending) / float64(m.sampleRate)
+	m.pending = 0
+
+	levels := Levels{
+		Timecode:   m.timecode,
+		SampleRate: m.sampleRate,
+		Channels:   make([]ChannelLevels, len(m.channels)),
+		Momentary:  m.loudness.momentary(),
+		ShortTerm:  m.loudness.shortTerm(),
+		Integrated: m.loudness.integrated(),
+	}
+
+	for ch := range m.channels {
+		c := &m.channels[ch]
+		if c.blockPeak > c.maxPeak {
+			c.maxPeak = c.blockPeak
+		}
+		if c.blockTruePeak > c.maxTruePeak {
+			c.maxTruePeak = c.blockTruePeak
+		}
+
+		levels.Channels[ch] = ChannelLevels{
+			Peak:        c.peak.update(toDB(c.blockPeak), elapsed, m.config),
+			RMS:         toDB(math.Sqrt(c.meanSquare)),
+			TruePeak:    c.truePeakHold.update(toDB(c.blockTruePeak), elapsed, m.config),
+			MaxPeak:     toDB(c.maxPeak),
+			MaxTruePeak: toDB(c.maxTruePeak),
+		}
+		c.blockPeak = 0
+		c.blockTruePeak = 0
+	}
+
+	m.mu.Lock()
+	m.current = levels
+	if m.updates != nil {
+		select {
+		case <-m.updates:
+		default:
+		}
+		m.updates <- levels
+	}
+	m.mu.Unlock()
+
+	if m.config.OnLevels != nil {
+		m.config.OnLevels(levels)
+	}
+}
+
+// Feed the peak of the last interval into the ballistics, and return the value to display.
+func (b *ballistic) update(db float64, elapsed float64, config MeterConfig) float64 {
+	if db >= b.value {
+		b.value = db
+		b.hold = config.PeakHold.Seconds()
+		return b.value
+	}
+
+	if b.hold > 0 {
+		b.hold -= elapsed
+	} else {
+		b.value -= config.PeakDecay * elapsed
+	}
+	if b.value < db {
+		b.value = db
+	}
+	// Let the meter fall to silence instead of decaying forever
+	if b.value < -200 {
+		b.value = math.Inf(-1)
+	}
+
+	return b.value
+}
+
+func silentLevels(sampleRate int32, numChannels int) Levels {
+	return Levels{
+		SampleRate: sampleRate,
+		Channels:   make([]ChannelLevels, numChannels),
+		Momentary:  math.Inf(-1),
+		ShortTerm:  math.Inf(-1),
+		Integrated: math.Inf(-1),
+	}
+}
+
+// Detects inter-sample peaks by oversampling, as described in ITU-R BS.1770-4 annex 2.
+type truePeakDetector struct {
+	factor  int
+	taps    int
+	phases  [][]float64
+	history []float64
+	pos     int
+}
+
+func newTruePeakDetector(sampleRate int32) truePeakDetector {
+	factor := 4
+	if sampleRate >= 192000 {
+		factor = 1
+	} else if sampleRate >= 96000 {
+		factor = 2
+	}
+
+	const taps = 12
+	d := truePeakDetector{
+		factor:  factor,
+		taps:    taps,
+		phases:  make([][]float64, factor),
+		history: make([]float64, taps),
+	}
+
+	// Windowed sinc interpolation filter, split into one polyphase branch per output sample
+	length := factor * taps
+	center := float64(length-1) / 2
+	for p := 0; p < factor; p++ {
+		d.phases[p] = make([]float64, taps)
+		var sum float64
+		for k := 0; k < taps; k++ {
+			n := float64(k*factor + p)
+			h := sinc((n-center)/float64(factor)) * kaiser((n-center)/(center+1), 8)
+			d.phases[p][k] = h
+			sum += h
+		}
+		for k := range d.phases[p] {
+			d.phases[p][k] /= sum
+		}
+	}
+
+	return d
+}
+
+// Push one sample, and return the highest absolute value of the oversampled signal.
+func (d *truePeakDetector) process(x float64) float64 {
+	d.history[d.pos] = x
+	d.pos = (d.pos + 1) % d.taps
+
+	if d.factor == 1 {
+		return math.Abs(x)
+	}
+
+	var peak float64
+	for _, phase := range d.phases {
+		var y float64
+		i := d.pos
+		for k := d.taps - 1; k >= 0; k-- {
+			y += d.history[i] * phase[k]
+			i++
+			if i == d.taps {
+				i = 0
+			}
+		}
+		if a := math.Abs(y); a > peak {
+			peak = a
+		}
+	}
+
+	return peak
+}
@@ -0,0 +1,105 @@
+package audio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bitfocus/gondi"
+)
+
+// Generate a planar frame with the same sine wave on every channel.
+func sineFrame(sampleRate int32, numChannels int32, numSamples int32, frequency float64, amplitude float64, offset int) *gondi.AudioFrameV2 {
+	data := make([]float32, numChannels*numSamples)
+	for ch := int32(0); ch < numChannels; ch++ {
+		for i := int32(0); i < numSamples; i++ {
+			t := float64(offset+int(i)) / float64(sampleRate)
+			data[ch*numSamples+i] = float32(amplitude * math.Sin(2*math.Pi*frequency*t))
+		}
+	}
+
+	frame := gondi.NewAudioFrameV2()
+	frame.SampleRate = sampleRate
+	frame.NumChannels = numChannels
+	frame.NumSamples = numSamples
+	frame.ChannelStride = numSamples * 4
+	frame.Data = &data[0]
+
+	return frame
+}
+
+func TestMeterSine(t *testing.T) {
+	m := NewMeter(nil)
+
+	// 10 seconds of a -20dBFS 1kHz sine on both channels of a stereo stream
+	amplitude := math.Pow(10, -20.0/20)
+	for i := 0; i < 500; i++ {
+		m.Process(sineFrame(48000, 2, 960, 1000, amplitude, i*960))
+	}
+
+	levels := m.Current()
+	if len(levels.Channels) != 2 {
+		t.Fatalf("got %d channels, want 2", len(levels.Channels))
+	}
+	for ch, c := range levels.Channels {
+		if math.Abs(c.Peak+20) > 0.1 {
+			t.Errorf("channel %d peak is %.2f dBFS, want -20", ch, c.Peak)
+		}
+		if math.Abs(c.RMS+23.01) > 0.1 {
+			t.Errorf("channel %d RMS is %.2f dBFS, want -23.01", ch, c.RMS)
+		}
+		if math.Abs(c.TruePeak+20) > 0.2 {
+			t.Errorf("channel %d true-peak is %.2f dBTP, want -20", ch, c.TruePeak)
+		}
+	}
+
+	// A 1kHz sine in both channels of a stereo signal reads close to its peak level in LUFS
+	for name, lufs := range map[string]float64{"momentary": levels.Momentary, "short-term": levels.ShortTerm, "integrated": levels.Integrated} {
+		if math.Abs(lufs+20) > 0.2 {
+			t.Errorf("%s loudness is %.2f LUFS, want -20", name, lufs)
+		}
+	}
+}
+
+func TestMeterTruePeak(t *testing.T) {
+	m := NewMeter(nil)
+
+	// A sine at a quarter of the sample rate, sampled 45 degrees off its peaks, has sample peaks 3dB below the true peak
+	data := make([]float32, 48000)
+	for i := range data {
+		data[i] = float32(0.5 * math.Sin(math.Pi/2*float64(i)+math.Pi/4))
+	}
+	frame := gondi.NewAudioFrameV2()
+	frame.SampleRate = 48000
+	frame.NumChannels = 1
+	frame.NumSamples = int32(len(data))
+	frame.Data = &data[0]
+	m.Process(frame)
+
+	c := m.Current().Channels[0]
+	if math.Abs(c.MaxPeak-(-6.02-3.01)) > 0.1 {
+		t.Errorf("max peak is %.2f dBFS, want -9.03", c.MaxPeak)
+	}
+	if math.Abs(c.MaxTruePeak+6.02) > 0.5 {
+		t.Errorf("max true-peak is %.2f dBTP, want -6.02", c.MaxTruePeak)
+	}
+}
+
+func TestMeterDecay(t *testing.T) {
+	m := NewMeter(&MeterConfig{PeakDecay: 10})
+	updates := m.Updates()
+
+	m.Process(sineFrame(48000, 1, 4800, 1000, 1, 0))
+
+	// 3 seconds of silence: 1.5 seconds hold, then 1.5 seconds of 10dB/s decay
+	for i := 0; i < 30; i++ {
+		m.Process(sineFrame(48000, 1, 4800, 1000, 0, 0))
+	}
+
+	levels := <-updates
+	if p := levels.Channels[0].Peak; math.Abs(p+15) > 0.6 {
+		t.Errorf("peak after decay is %.2f dBFS, want -15", p)
+	}
+	if p := levels.Channels[0].MaxPeak; math.Abs(p) > 0.01 {
+		t.Errorf("max peak is %.2f dBFS, want 0", p)
+	}
+}
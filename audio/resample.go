@@ -0,0 +1,201 @@
+package audio
+
+import (
+	"math"
+
+	"github.com/bitfocus/gondi"
+)
+
+// Quality of the sample rate conversion.
+type ResamplerQuality int
+
+const (
+	// Linear interpolation. Cheap, but it aliases when converting to a lower rate.
+	ResamplerQualityLow ResamplerQuality = iota
+	// Windowed sinc interpolation with 16 taps.
+	ResamplerQualityMedium
+	// Windowed sinc interpolation with 64 taps.
+	ResamplerQualityHigh
+)
+
+const (
+	// Number of filter table entries per input sample.
+	resamplerTableResolution = 512
+	// The largest drift ratio accepted by SetDriftRatio, as a deviation from 1.0.
+	resamplerMaxDrift = 0.01
+)
+
+// A Resampler converts audio of any sample rate to frames with a fixed sample rate and number of samples,
+// for instance to feed SendInstance.SendAudioFrame at a constant cadence.
+//
+// When the input comes from a clock that drifts compared to the output clock, the measured ratio between
+// the two can be set with SetDriftRatio, and the resampler will consume the input at that rate.
+type Resampler struct {
+	sampleRate      int32
+	samplesPerFrame int32
+	quality         ResamplerQuality
+
+	inputRate int32
+	drift     float64
+	step      float64
+
+	// Filter kernel, sampled resamplerTableResolution times per input sample, for distances 0 to width.
+	table []float64
+	width int
+
+	buffers [][]float32
+	pos     float64
+
+	out  *gondi.AudioFrameV2
+	data []float32
+	fill int32
+}
+
+// Create a resampler emitting frames of samplesPerFrame samples at the given sample rate.
+func NewResampler(sampleRate int32, samplesPerFrame int32, quality ResamplerQuality) *Resampler {
+	if sampleRate <= 0 || samplesPerFrame <= 0 {
+		panic("sample rate and samples per frame must be positive")
+	}
+
+	return &Resampler{
+		sampleRate:      sampleRate,
+		samplesPerFrame: samplesPerFrame,
+		quality:         quality,
+		drift:           1,
+	}
+}
+
+// Set the measured ratio between the actual and the nominal sample rate of the input. A ratio above 1 means
+// that the input produces samples faster than its nominal rate, so more input is consumed for each output frame.
+// The ratio is limited to 1% of deviation, and it is safe to change it between calls to Process.
+func (r *Resampler) SetDriftRatio(ratio float64) {
+	if ratio <= 0 || math.IsNaN(ratio) {
+		ratio = 1
+	}
+	r.drift = math.Max(1-resamplerMaxDrift, math.Min(1+resamplerMaxDrift, ratio))
+	if r.inputRate != 0 {
+		r.step = float64(r.inputRate) / float64(r.sampleRate) * r.drift
+	}
+}
+
+// Get the current drift ratio.
+func (r *Resampler) DriftRatio() float64 {
+	return r.drift
+}
+
+// Resample an input frame, and call emit for each complete output frame. The output frame and its buffer are
+// reused for every call to emit, so emit must send or copy the frame before it returns.
+// If the sample rate or channel count of the input changes, the resampler restarts from silence.
+func (r *Resampler) Process(frame *gondi.AudioFrameV2, emit func(*gondi.AudioFrameV2)) {
+	if frame == nil || frame.NumChannels <= 0 || frame.SampleRate <= 0 {
+		return
+	}
+	if frame.SampleRate != r.inputRate || int(frame.NumChannels) != len(r.buffers) {
+		r.setup(frame.SampleRate, int(frame.NumChannels))
+	}
+
+	for ch := range r.buffers {
+		r.buffers[ch] = append(r.buffers[ch], channel(frame, ch)...)
+	}
+	available := len(r.buffers[0])
+
+	for int(r.pos)+r.width < available {
+		for ch, buffer := range r.buffers {
+			r.data[int32(ch)*r.samplesPerFrame+r.fill] = r.interpolate(buffer, r.pos)
+		}
+		r.pos += r.step
+
+		r.fill++
+		if r.fill == r.samplesPerFrame {
+			emit(r.out)
+			r.fill = 0
+		}
+	}
+
+	// Drop the input that is no longer needed by the filter
+	if drop := int(r.pos) - r.width + 1; drop > 0 {
+		for ch, buffer := range r.buffers {
+			n := copy(buffer, buffer[drop:])
+			r.buffers[ch] = buffer[:n]
+		}
+		r.pos -= float64(drop)
+	}
+}
+
+// Discard all buffered audio and any partially filled output frame.
+func (r *Resampler) Reset() {
+	if r.inputRate != 0 {
+		r.setup(r.inputRate, len(r.buffers))
+	}
+}
+
+func (r *Resampler) setup(inputRate int32, numChannels int) {
+	r.inputRate = inputRate
+	r.step = float64(inputRate) / float64(r.sampleRate) * r.drift
+
+	var taps int
+	var rolloff, beta float64
+	switch r.quality {
+	case ResamplerQualityLow:
+		taps = 2
+	case ResamplerQualityMedium:
+		taps, rolloff, beta = 16, 0.9, 7
+	default:
+		taps, rolloff, beta = 64, 0.95, 9
+	}
+
+	// The cutoff is lowered when downsampling, which widens the kernel in input samples
+	cutoff := 1.0
+	if inputRate != r.sampleRate && taps > 2 {
+		cutoff = math.Min(1, float64(r.sampleRate)/float64(inputRate)) * rolloff
+	}
+	half := float64(taps/2) / cutoff
+	r.width = int(math.Ceil(half))
+
+	r.table = make([]float64, r.width*resamplerTableResolution+2)
+	for i := range r.table {
+		d := float64(i) / resamplerTableResolution
+		if taps == 2 {
+			r.table[i] = math.Max(0, 1-d)
+		} else {
+			r.table[i] = cutoff * sinc(cutoff*d) * kaiser(d/half, beta)
+		}
+	}
+
+	// Start with silence before the first sample, so the first output sample lines up with the first input sample
+	r.buffers = make([][]float32, numChannels)
+	for ch := range r.buffers {
+		r.buffers[ch] = make([]float32, r.width, r.width+8192)
+	}
+	r.pos = float64(r.width)
+
+	r.data = make([]float32, numChannels*int(r.samplesPerFrame))
+	r.fill = 0
+	r.out = gondi.NewAudioFrameV2()
+	r.out.SampleRate = r.sampleRate
+	r.out.NumChannels = int32(numChannels)
+	r.out.NumSamples = r.samplesPerFrame
+	r.out.ChannelStride = r.samplesPerFrame * 4
+	r.out.Data = &r.data[0]
+}
+
+// Get the filtered value of the input at a fractional position.
+func (r *Resampler) interpolate(buffer []float32, pos float64) float32 {
+	base := int(pos)
+	if r.step == 1 && pos == float64(base) {
+		return buffer[base]
+	}
+
+	var sum float64
+	for k := base - r.width + 1; k <= base+r.width; k++ {
+		d := math.Abs(pos-float64(k)) * resamplerTableResolution
+		i := int(d)
+		if i+1 >= len(r.table) {
+			continue
+		}
+		h := r.table[i] + (r.table[i+1]-r.table[i])*(d-float64(i))
+		sum += float64(buffer[k]) * h
+	}
+
+	return float32(sum)
+}
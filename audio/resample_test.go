@@ -0,0 +1,69 @@
+package audio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bitfocus/gondi"
+)
+
+func testResampler(t *testing.T, inputRate int32, quality ResamplerQuality, tolerance float64) {
+	r := NewResampler(48000, 960, quality)
+
+	var output []float32
+	var frames int
+	for i := 0; i < 10; i++ {
+		r.Process(sineFrame(inputRate, 2, inputRate/10, 1000, 0.5, i*int(inputRate/10)), func(frame *gondi.AudioFrameV2) {
+			if frame.SampleRate != 48000 || frame.NumSamples != 960 || frame.NumChannels != 2 {
+				t.Fatalf("unexpected output format %d Hz, %d samples, %d channels", frame.SampleRate, frame.NumSamples, frame.NumChannels)
+			}
+			output = append(output, channel(frame, 1)...)
+			frames++
+		})
+	}
+
+	// One second of input, minus the filter look-ahead
+	if frames != 49 {
+		t.Errorf("got %d frames, want 49", frames)
+	}
+
+	// Skip the start, where the filter sees the silence before the first sample
+	var maxError float64
+	for j := 200; j < len(output); j++ {
+		want := 0.5 * math.Sin(2*math.Pi*1000*float64(j)/48000)
+		maxError = math.Max(maxError, math.Abs(float64(output[j])-want))
+	}
+	if maxError > tolerance {
+		t.Errorf("%d Hz to 48000 Hz: max error %.6f, want below %.6f", inputRate, maxError, tolerance)
+	}
+}
+
+func TestResampler(t *testing.T) {
+	testResampler(t, 48000, ResamplerQualityHigh, 1e-7)
+	testResampler(t, 44100, ResamplerQualityHigh, 1e-3)
+	testResampler(t, 96000, ResamplerQualityHigh, 1e-3)
+	testResampler(t, 44100, ResamplerQualityMedium, 1e-2)
+	testResampler(t, 44100, ResamplerQualityLow, 5e-2)
+}
+
+func TestResamplerDrift(t *testing.T) {
+	r := NewResampler(48000, 1000, ResamplerQualityMedium)
+	r.SetDriftRatio(1.001)
+
+	var samples int
+	for i := 0; i < 10; i++ {
+		r.Process(sineFrame(48000, 1, 48000, 1000, 0.5, i*48000), func(frame *gondi.AudioFrameV2) {
+			samples += int(frame.NumSamples)
+		})
+	}
+
+	// Consuming the input 0.1% faster gives 0.1% fewer output samples
+	if want := 480000 / 1.001; math.Abs(float64(samples)-want) > 1000 {
+		t.Errorf("got %d samples, want about %.0f", samples, want)
+	}
+
+	r.SetDriftRatio(2)
+	if r.DriftRatio() != 1.01 {
+		t.Errorf("drift ratio %f was not limited to 1.01", r.DriftRatio())
+	}
+}
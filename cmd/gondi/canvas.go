@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bitfocus/gondi"
+)
+
+type rgb struct {
+	r, g, b uint8
+}
+
+var (
+	black = rgb{0, 0, 0}
+	white = rgb{255, 255, 255}
+	red   = rgb{255, 0, 0}
+	green = rgb{0, 255, 0}
+)
+
+// Convert to BT.709 video range YCbCr.
+func (c rgb) ycbcr() (y, cb, cr uint8) {
+	r, g, b := float64(c.r), float64(c.g), float64(c.b)
+	return uint8(16.5 + (0.2126*r+0.7152*g+0.0722*b)*219/255),
+		uint8(128.5 + (-0.1146*r-0.3854*g+0.5*b)*224/255),
+		uint8(128.5 + (0.5*r-0.4542*g-0.0458*b)*224/255)
+}
+
+// A canvas draws into the video data of a frame in its FourCC. For the 4:2:2 formats, rectangles are aligned to
+// even pixels, so each pixel pair has a single color.
+type canvas struct {
+	data          []byte
+	fourcc        gondi.FourCCType
+	width, height int
+	stride        int
+}
+
+func newCanvas(frame *gondi.VideoFrameV2, data []byte) (*canvas, error) {
+	switch frame.FourCC {
+	case gondi.FourCCTypeUYVY, gondi.FourCCTypeUYVA, gondi.FourCCTypeBGRA, gondi.FourCCTypeBGRX:
+	default:
+		return nil, fmt.Errorf("unsupported FourCC %s", frame.FourCC)
+	}
+
+	stride := int(frame.LineStride)
+	if stride == 0 {
+		stride = int(frame.FourCC.LineStride(frame.Xres))
+	}
+
+	return &canvas{data, frame.FourCC, int(frame.Xres), int(frame.Yres), stride}, nil
+}
+
+// Get the bytes of a single pixel pair in the FourCC.
+func (c *canvas) pair(color rgb) [8]byte {
+	if c.fourcc == gondi.FourCCTypeUYVY || c.fourcc == gondi.FourCCTypeUYVA {
+		y, cb, cr := color.ycbcr()
+		return [8]byte{cb, y, cr, y}
+	}
+	return [8]byte{color.b, color.g, color.r, 0xff, color.b, color.g, color.r, 0xff}
+}
+
+// Get the number of bytes used by a pixel pair.
+func (c *canvas) pairSize() int {
+	if c.fourcc == gondi.FourCCTypeUYVY || c.fourcc == gondi.FourCCTypeUYVA {
+		return 4
+	}
+	return 8
+}
+
+// Set a single pixel to a grey value, at full horizontal resolution also for the 4:2:2 formats.
+func (c *canvas) setGrey(x, y int, v uint8) {
+	if x < 0 || y < 0 || x >= c.width || y >= c.height {
+		return
+	}
+	if c.fourcc == gondi.FourCCTypeUYVY || c.fourcc == gondi.FourCCTypeUYVA {
+		luma, _, _ := rgb{v, v, v}.ycbcr()
+		offset := y*c.stride + x/2*4
+		c.data[offset+x%2*2] = 128
+		c.data[offset+x%2*2+1] = luma
+		return
+	}
+	offset := y*c.stride + x*4
+	c.data[offset], c.data[offset+1], c.data[offset+2], c.data[offset+3] = v, v, v, 0xff
+}
+
+// Fill a rectangle, clipped to the canvas.
+func (c *canvas) fill(x, y, w, h int, color rgb) {
+	x0, y0, x1, y1 := max(x, 0)&^1, max(y, 0), min(x+w, c.width), min(y+h, c.height)
+	if x0 >= x1 || y0 >= y1 {
+		return
+	}
+
+	pair := c.pair(color)
+	size := c.pairSize()
+	start, end := x0/2*size, (x1+1)/2*size
+
+	row := c.data[y0*c.stride+start : y0*c.stride+end]
+	for i := 0; i < len(row); i += size {
+		copy(row[i:], pair[:size])
+	}
+	for line := y0 + 1; line < y1; line++ {
+		copy(c.data[line*c.stride+start:line*c.stride+end], row)
+	}
+}
+
+// Draw the outline of a rectangle with the given thickness.
+func (c *canvas) border(x, y, w, h, thickness int, color rgb) {
+	c.fill(x, y, w, thickness, color)
+	c.fill(x, y+h-thickness, w, thickness, color)
+	c.fill(x, y, thickness, h, color)
+	c.fill(x+w-thickness, y, thickness, h, color)
+}
+
+// A 5x7 pixel font for timecodes and counters.
+var font = map[rune][7]uint8{
+	'0': {0x0e, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0e},
+	'1': {0x04, 0x0c, 0x04, 0x04, 0x04, 0x04, 0x0e},
+	'2': {0x0e, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1f},
+	'3': {0x1f, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0e},
+	'4': {0x02, 0x06, 0x0a, 0x12, 0x1f, 0x02, 0x02},
+	'5': {0x1f, 0x10, 0x1e, 0x01, 0x01, 0x11, 0x0e},
+	'6': {0x06, 0x08, 0x10, 0x1e, 0x11, 0x11, 0x0e},
+	'7': {0x1f, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0e, 0x11, 0x11, 0x0e, 0x11, 0x11, 0x0e},
+	'9': {0x0e, 0x11, 0x11, 0x0f, 0x01, 0x02, 0x0c},
+	':': {0x00, 0x0c, 0x0c, 0x00, 0x0c, 0x0c, 0x00},
+	';': {0x00, 0x0c, 0x0c, 0x00, 0x0c, 0x04, 0x08},
+	'#': {0x0a, 0x0a, 0x1f, 0x0a, 0x1f, 0x0a, 0x0a},
+}
+
+// Get the size of text drawn with the given scale.
+func textSize(text string, scale int) (w, h int) {
+	return len(text) * 6 * scale, 7 * scale
+}
+
+// Draw text with each font pixel as a scale by scale square. Characters without a glyph are left blank.
+func (c *canvas) text(x, y int, text string, scale int, color rgb) {
+	for i, ch := range text {
+		glyph := font[ch]
+		for row, bits := range glyph {
+			for col := 0; col < 5; col++ {
+				if bits&(0x10>>col) != 0 {
+					c.fill(x+(i*6+col)*scale, y+row*scale, scale, scale, color)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bitfocus/gondi"
+)
+
+func newTestCanvas(t *testing.T, fourcc gondi.FourCCType, width, height int32) (*canvas, []byte) {
+	frame := gondi.NewVideoFrameV2()
+	frame.Xres, frame.Yres, frame.FourCC = width, height, fourcc
+	data := make([]byte, frame.DataSize())
+	c, err := newCanvas(frame, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, data
+}
+
+func TestCanvasFill(t *testing.T) {
+	c, data := newTestCanvas(t, gondi.FourCCTypeUYVY, 8, 4)
+	c.fill(0, 0, 8, 4, black)
+	c.fill(3, 1, 2, 2, white)
+
+	// The rectangle starts at an odd pixel, so it is aligned to the pixel pair 2-3
+	want := []byte{128, 235, 128, 235}
+	if got := data[1*16+4 : 1*16+8]; string(got) != string(want) {
+		t.Errorf("white pixel pair is %v, want %v", got, want)
+	}
+	if got := data[0:4]; string(got) != string([]byte{128, 16, 128, 16}) {
+		t.Errorf("black pixel pair is %v", got)
+	}
+	if got := data[3*16+4 : 3*16+8]; string(got) != string([]byte{128, 16, 128, 16}) {
+		t.Errorf("pixels below the rectangle were changed: %v", got)
+	}
+
+	c, data = newTestCanvas(t, gondi.FourCCTypeBGRA, 4, 2)
+	c.fill(-2, -2, 100, 100, rgb{1, 2, 3})
+	for i := 0; i < len(data); i += 4 {
+		if string(data[i:i+4]) != string([]byte{3, 2, 1, 255}) {
+			t.Fatalf("pixel %d is %v", i/4, data[i:i+4])
+		}
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	for name, draw := range patterns {
+		for _, fourcc := range fourCCs {
+			c, _ := newTestCanvas(t, fourcc, 64, 36)
+			draw(c)
+			drawBox(c, 7, 25)
+			c.text(0, 0, "12:34:56;07 #8", 2, white)
+			c.border(0, 0, c.width, c.height, 2, red)
+		}
+		if t.Failed() {
+			t.Fatalf("pattern %s failed", name)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitfocus/gondi"
+)
+
+// Discovery options shared by all commands that look for sources.
+type finderFlags struct {
+	groups   string
+	extraIPs string
+	noLocal  bool
+	timeout  time.Duration
+}
+
+func (f *finderFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.groups, "groups", "", "comma separated list of groups to search, the default groups are used when empty")
+	fs.StringVar(&f.extraIPs, "extra-ips", "", "comma separated list of extra IP addresses to query for sources")
+	fs.BoolVar(&f.noLocal, "no-local", false, "hide sources running on this machine")
+}
+
+// Register the discovery flags, and a -timeout flag for how long to wait for sources.
+func (f *finderFlags) registerWithTimeout(fs *flag.FlagSet, timeout time.Duration) {
+	f.register(fs)
+	fs.DurationVar(&f.timeout, "timeout", timeout, "how long to wait for sources to be discovered")
+}
+
+func (f *finderFlags) open() (*gondi.FindInstance, error) {
+	return gondi.NewFindInstance(!f.noLocal, f.groups, f.extraIPs)
+}
+
+// Find a source by its full name, for instance "MACHINE (Camera 1)". If no source has that exact name, a unique
+// source containing it, ignoring case, is used. Discovery continues until the source is found or the timeout expires.
+func (f *finderFlags) find(ctx context.Context, name string) (*gondi.Source, error) {
+	finder, err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	defer finder.Close()
+
+	return waitForSource(ctx, finder, name, f.timeout)
+}
+
+// Like finderFlags.find, using an existing finder.
+func waitForSource(ctx context.Context, finder *gondi.FindInstance, name string, timeout time.Duration) (*gondi.Source, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		source, err := matchSource(finder.GetCurrentSources(), name)
+		if source != nil || err != nil {
+			return source, err
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("source %q not found", name)
+		}
+		finder.WaitForSources(100)
+	}
+}
+
+func matchSource(sources []*gondi.Source, name string) (*gondi.Source, error) {
+	var matches []*gondi.Source
+	for _, source := range sources {
+		if source.Name() == name {
+			return source, nil
+		}
+		if strings.Contains(strings.ToLower(source.Name()), strings.ToLower(name)) {
+			matches = append(matches, source)
+		}
+	}
+
+	if len(matches) > 1 {
+		names := make([]string, len(matches))
+		for i, match := range matches {
+			names[i] = match.Name()
+		}
+		return nil, fmt.Errorf("%q matches several sources: %s", name, strings.Join(names, ", "))
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+
+	return nil, nil
+}
+
+// Wait until no new sources show up for a while, or until the timeout expires, and return the sources.
+func settleSources(ctx context.Context, finder *gondi.FindInstance, timeout time.Duration) []*gondi.Source {
+	deadline := time.Now().Add(timeout)
+	for ctx.Err() == nil && time.Now().Before(deadline) {
+		wait := time.Until(deadline)
+		if wait > time.Second {
+			wait = time.Second
+		}
+		if !finder.WaitForSources(uint32(wait.Milliseconds())) && len(finder.GetCurrentSources()) > 0 {
+			break
+		}
+	}
+
+	return finder.GetCurrentSources()
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bitfocus/gondi"
+	"github.com/bitfocus/gondi/metadata"
+)
+
+var inspectCommand = &command{
+	name:  "inspect",
+	usage: "[options] <source>",
+	short: "Connect to a source and print its format, connection metadata and capabilities",
+	run:   runInspect,
+}
+
+func runInspect(ctx context.Context, cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	var find finderFlags
+	find.registerWithTimeout(fs, 5*time.Second)
+	listen := fs.Duration("listen", 3*time.Second, "how long to receive frames once connected")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	source, err := find.find(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	receiver, err := gondi.NewRecvInstanceWithOptions(
+		gondi.WithRecvSource(source),
+		gondi.WithRecvName("gondi inspect"),
+	)
+	if err != nil {
+		return err
+	}
+	defer receiver.Close()
+
+	var (
+		video    *gondi.VideoFrameV2
+		audio    *gondi.AudioFrameV2
+		messages []string
+	)
+
+	// Connection metadata is delivered as the first metadata frames, keep receiving until both video and audio
+	// have been seen, or the listen time is over
+	deadline := time.Now().Add(*listen)
+	for ctx.Err() == nil && time.Now().Before(deadline) && (video == nil || audio == nil) {
+		vf, af, mf := gondi.NewVideoFrameV2(), gondi.NewAudioFrameV2(), &gondi.MetadataFrame{}
+
+		switch receiver.CaptureV2(vf, af, mf, 100) {
+		case gondi.FrameTypeVideo:
+			if video == nil {
+				video = vf
+				video.Data = nil
+				video.Metadata = nil
+			}
+			receiver.FreeVideoV2(vf)
+		case gondi.FrameTypeAudio:
+			if audio == nil {
+				audio = af
+				audio.Data = nil
+				audio.Metadata = nil
+			}
+			receiver.FreeAudioV2(af)
+		case gondi.FrameTypeMetadata:
+			messages = append(messages, mf.GetData())
+			receiver.FreeMetadata(mf)
+		case gondi.FrameTypeError:
+			return fmt.Errorf("connection to %q failed", source.Name())
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Source:\t%s\n", source.Name())
+	fmt.Fprintf(w, "Address:\t%s\n", source.Address())
+	fmt.Fprintf(w, "Connected:\t%v\n", receiver.GetNumberOfConnections() > 0)
+
+	if video != nil {
+		fmt.Fprintf(w, "Video:\t%dx%d %s %s\n", video.Xres, video.Yres, video.FourCC, frameFormats[video.FrameFormatType])
+		fmt.Fprintf(w, "Frame rate:\t%s (%.3f fps)\n", video.GetFrameRate(), video.GetFrameRate().Float64())
+		if video.PictureAspectRatio != 0 {
+			fmt.Fprintf(w, "Aspect ratio:\t%.3f\n", video.PictureAspectRatio)
+		}
+		fmt.Fprintf(w, "Line stride:\t%d\n", video.LineStride)
+		if tc := video.GetTimecode(); !tc.IsUndefined() {
+			fmt.Fprintf(w, "Timecode:\t%s\n", tc.FormatSMPTE(video.FrameRateN, video.FrameRateD))
+		}
+	} else {
+		fmt.Fprintf(w, "Video:\tnone received\n")
+	}
+
+	if audio != nil {
+		fmt.Fprintf(w, "Audio:\t%d Hz, %d channels, %d samples per frame\n", audio.SampleRate, audio.NumChannels, audio.NumSamples)
+	} else {
+		fmt.Fprintf(w, "Audio:\tnone received\n")
+	}
+
+	fmt.Fprintf(w, "PTZ:\t%v\n", receiver.PTZIsSupported())
+	if url := receiver.GetWebControl(); url != "" {
+		fmt.Fprintf(w, "Web control:\t%s\n", url)
+	}
+
+	for _, message := range messages {
+		decoded, err := metadata.Decode(message)
+		if err != nil {
+			continue
+		}
+		switch m := decoded.(type) {
+		case *metadata.Product:
+			fmt.Fprintf(w, "Product:\t%s %s %s\n", m.Manufacturer, m.LongName, m.Version)
+		case *metadata.Capabilities:
+			fmt.Fprintf(w, "Capabilities:\t%s\n", capabilityFlags(m))
+		}
+	}
+	w.Flush()
+
+	if len(messages) > 0 {
+		fmt.Println("\nMetadata:")
+		for _, message := range messages {
+			fmt.Printf("  %s\n", message)
+		}
+	}
+
+	return nil
+}
+
+// List the capabilities that are set.
+func capabilityFlags(c *metadata.Capabilities) string {
+	flags := []struct {
+		name string
+		set  bool
+	}{
+		{"ptz", c.PTZ}, {"pan_tilt", c.PanTilt}, {"zoom", c.Zoom}, {"iris", c.Iris},
+		{"white_balance", c.WhiteBalance}, {"exposure", c.Exposure}, {"exposure_v2", c.ExposureV2},
+		{"focus", c.Focus}, {"autofocus", c.AutoFocus}, {"record", c.Record}, {"kvm", c.KVM},
+	}
+
+	list := ""
+	for _, flag := range flags {
+		if flag.set {
+			if list != "" {
+				list += " "
+			}
+			list += flag.name
+		}
+	}
+	if list == "" {
+		list = "none"
+	}
+	if c.WebControl != "" {
+		list += ", web control " + c.WebControl
+	}
+
+	return list
+}
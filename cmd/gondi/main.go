@@ -0,0 +1,132 @@
+// Command gondi lists, inspects and works with NDI sources from the command line.
+//
+//	gondi [-library path] <command> [arguments]
+//
+// Run "gondi help" for the list of commands, and "gondi <command> -h" for the options of a command.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/bitfocus/gondi"
+)
+
+type command struct {
+	name  string
+	usage string
+	short string
+	run   func(ctx context.Context, cmd *command, args []string) error
+}
+
+var commands = []*command{
+	sourcesCommand,
+	watchCommand,
+	inspectCommand,
+	testSignalCommand,
+	recordCommand,
+	playCommand,
+	routeCommand,
+	monitorCommand,
+	metaCommand,
+	tallyCommand,
+	serveMetaCommand,
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: gondi [-library path] <command> [arguments]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.name, cmd.short)
+	}
+	fmt.Fprintf(os.Stderr, "\nGlobal options:\n")
+	flag.PrintDefaults()
+}
+
+var library = flag.String("library", "", "path to the NDI library, the platform default is used when empty")
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 || flag.Arg(0) == "help" {
+		usage()
+		os.Exit(2)
+	}
+
+	var cmd *command
+	for _, c := range commands {
+		if c.name == flag.Arg(0) {
+			cmd = c
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "gondi: unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	// Commands run until they are done, or until interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := cmd.run(ctx, cmd, flag.Args()[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "gondi %s: %v\n", cmd.name, err)
+		os.Exit(1)
+	}
+}
+
+// Create the flag set of a command, with a usage message listing its arguments.
+func newFlagSet(cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gondi %s %s\n\n%s\n\nOptions:\n", cmd.name, cmd.usage, cmd.short)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// Parse the flags of a command, check that exactly n arguments follow them, and load the NDI library.
+func parseArgs(fs *flag.FlagSet, args []string, n int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != n {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	return initLibrary()
+}
+
+// Load the NDI library. InitLibrary panics when the library cannot be opened, which is reported as an error here.
+func initLibrary() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("unable to load the NDI library: %v", r)
+		}
+	}()
+
+	return gondi.InitLibrary(*library)
+}
+
+// A flag that can be repeated, collecting all values.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
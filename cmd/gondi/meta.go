@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bitfocus/gondi"
+	"github.com/bitfocus/gondi/metadata"
+)
+
+var metaCommand = &command{
+	name:  "meta",
+	usage: "tail|send [options] <source> [xml]",
+	short: "Print the metadata sent by a source, or send metadata to it",
+	run:   runMeta,
+}
+
+var tallyCommand = &command{
+	name:  "tally",
+	usage: "[options] <source>",
+	short: "Hold a program and preview tally on a source until interrupted",
+	run:   runTally,
+}
+
+var serveMetaCommand = &command{
+	name:  "serve-meta",
+	usage: "[options] <name>",
+	short: "Run a metadata only sender, and print the metadata receivers send to it",
+	run:   runServeMeta,
+}
+
+func runMeta(ctx context.Context, cmd *command, args []string) error {
+	if len(args) == 0 {
+		newFlagSet(cmd).Usage()
+		return flag.ErrHelp
+	}
+
+	switch args[0] {
+	case "tail":
+		return runMetaTail(ctx, &command{name: "meta tail", usage: "[options] <source>", short: "Print the metadata sent by a source"}, args[1:])
+	case "send":
+		return runMetaSend(ctx, &command{name: "meta send", usage: "[options] <source> <xml|->", short: "Send metadata to a source, - reads it from stdin"}, args[1:])
+	default:
+		newFlagSet(cmd).Usage()
+		return flag.ErrHelp
+	}
+}
+
+// Connect a receiver that only receives metadata.
+func connectMetadataOnly(ctx context.Context, find *finderFlags, name, receiverName string) (*gondi.Source, *gondi.RecvInstance, error) {
+	source, err := find.find(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	receiver, err := gondi.NewRecvInstanceWithOptions(
+		gondi.WithRecvSource(source),
+		gondi.WithRecvName(receiverName),
+		gondi.WithRecvBandwidth(gondi.RecvBandwidthMetadataOnly),
+	)
+	return source, receiver, err
+}
+
+func runMetaTail(ctx context.Context, cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	var find finderFlags
+	find.registerWithTimeout(fs, 5*time.Second)
+	asJSON := fs.Bool("json", false, "print one JSON object per message")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	source, receiver, err := connectMetadataOnly(ctx, &find, fs.Arg(0), "gondi meta tail")
+	if err != nil {
+		return err
+	}
+	defer receiver.Close()
+
+	fmt.Fprintf(os.Stderr, "Receiving metadata from %s, interrupt to stop\n", source.Name())
+
+	for ctx.Err() == nil {
+		mf := &gondi.MetadataFrame{}
+		switch receiver.CaptureV2(nil, nil, mf, 100) {
+		case gondi.FrameTypeMetadata:
+			data, timecode := mf.GetData(), mf.GetTimecode()
+			receiver.FreeMetadata(mf)
+			printMetadata(os.Stdout, *asJSON, time.Now(), data, timecode)
+		case gondi.FrameTypeError:
+			return fmt.Errorf("connection to %q failed", source.Name())
+		}
+	}
+
+	return ctx.Err()
+}
+
+// A metadata message as printed with -json.
+type metadataLine struct {
+	Time     time.Time      `json:"time"`
+	Timecode gondi.Timecode `json:"timecode"`
+	Root     string         `json:"root,omitempty"`
+	Data     string         `json:"data"`
+}
+
+// Print a metadata message received at the given time, either as text or as a line of JSON.
+func printMetadata(w io.Writer, asJSON bool, received time.Time, data string, timecode gondi.Timecode) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(metadataLine{received, timecode, metadata.Root(data), data})
+	}
+	_, err := fmt.Fprintf(w, "%s %s\n", received.Format("15:04:05.000"), strings.TrimSpace(data))
+	return err
+}
+
+// Get the metadata to send from an argument, which is either an XML element or - to read it from stdin.
+func readMetadataArg(arg string, stdin io.Reader) (string, error) {
+	data := arg
+	if arg == "-" {
+		input, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", err
+		}
+		data = string(input)
+	}
+
+	data = strings.TrimSpace(data)
+	if err := validateMetadata(data); err != nil {
+		return "", err
+	}
+	return data, nil
+}
+
+// Check that metadata is a single, well formed XML element, as the SDK requires. Only an XML declaration, comments
+// and white space may surround the element.
+func validateMetadata(data string) error {
+	decoder := xml.NewDecoder(strings.NewReader(data))
+	depth, roots := 0, 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("metadata %q is not valid XML: %w", data, err)
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				roots++
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && len(bytes.TrimSpace(token)) > 0 {
+				return fmt.Errorf("metadata %q has text outside of its element", data)
+			}
+		}
+	}
+
+	if depth != 0 || roots != 1 {
+		return fmt.Errorf("metadata %q is not a single XML element", data)
+	}
+	return nil
+}
+
+func runMetaSend(ctx context.Context, cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	var find finderFlags
+	find.registerWithTimeout(fs, 5*time.Second)
+	if err := parseArgs(fs, args, 2); err != nil {
+		return err
+	}
+
+	data, err := readMetadataArg(fs.Arg(1), os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	source, receiver, err := connectMetadataOnly(ctx, &find, fs.Arg(0), "gondi meta send")
+	if err != nil {
+		return err
+	}
+	defer receiver.Close()
+
+	// Metadata can only be sent once the connection is up
+	deadline := time.Now().Add(find.timeout)
+	for receiver.GetNumberOfConnections() == 0 {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("unable to connect to %q", source.Name())
+		}
+		receiver.CaptureV2(nil, nil, nil, 100)
+	}
+
+	if err := receiver.SendMetadata(data); err != nil {
+		return fmt.Errorf("unable to send metadata to %q: %w", source.Name(), err)
+	}
+	fmt.Fprintf(os.Stderr, "Sent %s to %s\n", metadata.Root(data), source.Name())
+	return nil
+}
+
+func runTally(ctx context.Context, cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	var find finderFlags
+	find.registerWithTimeout(fs, 5*time.Second)
+	program := fs.Bool("program", false, "put the source on program")
+	preview := fs.Bool("preview", false, "put the source on preview")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	source, receiver, err := connectMetadataOnly(ctx, &find, fs.Arg(0), "gondi tally")
+	if err != nil {
+		return err
+	}
+	defer receiver.Close()
+
+	// The SDK sends the tally again whenever the connection is made, so it is set once, and held by staying connected
+	receiver.SetTally(*program, *preview)
+	fmt.Fprintf(os.Stderr, "Holding program %s, preview %s on %s, interrupt to release\n", onOff(*program), onOff(*preview), source.Name())
+
+	connected := false
+	for ctx.Err() == nil {
+		mf := &gondi.MetadataFrame{}
+		if receiver.CaptureV2(nil, nil, mf, 100) == gondi.FrameTypeMetadata {
+			receiver.FreeMetadata(mf)
+		}
+
+		if now := receiver.GetNumberOfConnections() > 0; now != connected {
+			connected = now
+			if connected {
+				fmt.Fprintf(os.Stderr, "Connected to %s\n", source.Name())
+			} else {
+				fmt.Fprintf(os.Stderr, "Disconnected from %s\n", source.Name())
+			}
+		}
+	}
+
+	return ctx.Err()
+}
+
+func runServeMeta(ctx context.Context, cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	groups := fs.String("groups", "", "comma separated list of groups to send in")
+	asJSON := fs.Bool("json", false, "print one JSON object per message")
+	var connectionMetadata stringList
+	fs.Var(&connectionMetadata, "connection-metadata", "XML sent to every receiver when it connects, can be repeated")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	for _, data := range connectionMetadata {
+		if err := validateMetadata(data); err != nil {
+			return err
+		}
+	}
+
+	sender, err := gondi.NewSendInstanceWithOptions(gondi.SendOptions{Name: fs.Arg(0), Groups: *groups})
+	if err != nil {
+		return err
+	}
+	defer sender.Close()
+
+	for i, data := range connectionMetadata {
+		if err := sender.ConnectionMetadata().Set(fmt.Sprintf("%d", i), data); err != nil {
+			return err
+		}
+	}
+
+	name := fs.Arg(0)
+	if source, err := sender.SourceName(); err == nil {
+		name = source.Name()
+	}
+	fmt.Fprintf(os.Stderr, "Serving %s, interrupt to stop\n", name)
+
+	// Tally and connection changes would break the JSON lines on stdout
+	if !*asJSON {
+		go reportSender(ctx, fs.Arg(0), sender)
+	}
+
+	for msg := range sender.WatchMetadata(ctx) {
+		printMetadata(os.Stdout, *asJSON, msg.Time, msg.Data, msg.Timecode)
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitfocus/gondi"
+)
+
+func TestMetaArgs(t *testing.T) {
+	// Wrong arguments are rejected before the library is loaded
+	tests := []struct {
+		cmd  *command
+		args []string
+	}{
+		{metaCommand, nil},
+		{metaCommand, []string{"follow", "A (Camera 1)"}},
+		{metaCommand, []string{"tail"}},
+		{metaCommand, []string{"tail", "A (Camera 1)", "B (Camera 2)"}},
+		{metaCommand, []string{"send", "A (Camera 1)"}},
+		{tallyCommand, []string{"-program"}},
+		{serveMetaCommand, nil},
+	}
+	for _, test := range tests {
+		if err := test.cmd.run(context.Background(), test.cmd, test.args); !errors.Is(err, flag.ErrHelp) {
+			t.Errorf("gondi %s %q returned %v, want the usage", test.cmd.name, test.args, err)
+		}
+	}
+
+	if err := tallyCommand.run(context.Background(), tallyCommand, []string{"-on-air", "A (Camera 1)"}); err == nil {
+		t.Error("an unknown flag was accepted")
+	}
+}
+
+func TestReadMetadataArg(t *testing.T) {
+	data, err := readMetadataArg(` <ntk_ptz_zoom zoom="0.5"/> `, nil)
+	if err != nil || data != `<ntk_ptz_zoom zoom="0.5"/>` {
+		t.Errorf("got %q, %v", data, err)
+	}
+
+	data, err = readMetadataArg("-", strings.NewReader("<ntk_ptz_focus mode=\"auto\"/>\n"))
+	if err != nil || data != `<ntk_ptz_focus mode="auto"/>` {
+		t.Errorf("got %q, %v from stdin", data, err)
+	}
+
+	data, err = readMetadataArg(`<?xml version="1.0"?><!-- zoom --><ntk_ptz_zoom zoom="0.5"><speed>1</speed></ntk_ptz_zoom>`, nil)
+	if err != nil {
+		t.Errorf("a declaration, comment and child element were rejected: %v", err)
+	}
+
+	for _, invalid := range []string{"", "zoom=0.5", "<unterminated", "<a><b>", "<a/><b/>", "<a>text", "<a/>junk", "text<a/>", "<a></b>"} {
+		if _, err := readMetadataArg(invalid, nil); err == nil {
+			t.Errorf("%q was accepted", invalid)
+		}
+	}
+	if _, err := readMetadataArg("-", strings.NewReader("")); err == nil {
+		t.Error("empty stdin was accepted")
+	}
+}
+
+func TestPrintMetadata(t *testing.T) {
+	received := time.Date(2024, 3, 1, 12, 30, 15, 250e6, time.UTC)
+	data := `<ndi_tally_echo on_program="true" on_preview="false"/>`
+
+	var b bytes.Buffer
+	if err := printMetadata(&b, true, received, data, 1234); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(b.String(), "\n") != 1 {
+		t.Errorf("JSON output is not a single line: %q", b.String())
+	}
+	var line metadataLine
+	if err := json.Unmarshal(b.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	want := metadataLine{received, gondi.Timecode(1234), "ndi_tally_echo", data}
+	if line != want {
+		t.Errorf("printed %+v, want %+v", line, want)
+	}
+
+	b.Reset()
+	printMetadata(&b, false, received, data+"\n", 0)
+	if got := b.String(); got != "12:30:15.250 "+data+"\n" {
+		t.Errorf("printed %q", got)
+	}
+}
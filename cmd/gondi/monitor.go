@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitfocus/gondi"
+	"github.com/bitfocus/gondi/audio"
+	"github.com/bitfocus/gondi/metadata"
+)
+
+var monitorCommand = &command{
+	name:  "monitor",
+	usage: "[options] <source>",
+	short: "Print per second frame, drop, queue, bitrate, audio level and tally statistics of a source",
+	run:   runMonitor,
+}
+
+// Counters updated by the capture loop of monitor.
+//
+// The standard SDK only hands out decoded frames, and does not report the bitrate of the stream on the network, so
+// the bytes are then counted after decoding, and the throughput they give is many times the actual bitrate. With
+// -compressed, the receiver asks for the compressed video as sent, which needs the NDI Advanced SDK, and the size of
+// the compressed packets gives an estimate of the bitrate. Audio is always counted as the decoded samples.
+type monitorStats struct {
+	mu               sync.Mutex
+	video, audio     int64
+	metadata         int64
+	bytes            int64
+	format           string
+	program, preview bool
+	disconnected     bool
+}
+
+func runMonitor(ctx context.Context, cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	var find finderFlags
+	find.registerWithTimeout(fs, 5*time.Second)
+	interval := fs.Duration("interval", time.Second, "how often to print statistics")
+	lowest := fs.Bool("lowest", false, "receive the low bandwidth proxy stream")
+	compressed := fs.Bool("compressed", false, "receive compressed video to estimate the bitrate, needs the NDI Advanced SDK")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	source, err := find.find(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	bandwidth := gondi.RecvBandwidthHighest
+	if *lowest {
+		bandwidth = gondi.RecvBandwidthLowest
+	}
+	options := []gondi.RecvOption{
+		gondi.WithRecvSource(source),
+		gondi.WithRecvName("gondi monitor"),
+		gondi.WithRecvBandwidth(bandwidth),
+	}
+	throughputLabel := "decoded"
+	if *compressed {
+		options = append(options, gondi.WithRecvColorFormat(gondi.RecvColorFormatCompressedV5))
+		throughputLabel = "bitrate"
+	}
+	receiver, err := gondi.NewRecvInstanceWithOptions(options...)
+	if err != nil {
+		if *compressed {
+			return fmt.Errorf("%w, receiving compressed video needs the NDI Advanced SDK", err)
+		}
+		return err
+	}
+	defer receiver.Close()
+
+	stats := &monitorStats{}
+	meter := audio.NewMeter(nil)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		stats.capture(ctx, receiver, meter)
+	}()
+	defer wg.Wait()
+
+	fmt.Printf("Monitoring %s (%s)\n", source.Name(), source.Address())
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	_, lastDropped := receiver.GetPerformance()
+	var last monitorStats
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		_, dropped := receiver.GetPerformance()
+		queue := receiver.GetQueue()
+
+		stats.mu.Lock()
+		video, audioFrames, meta := stats.video-last.video, stats.audio-last.audio, stats.metadata-last.metadata
+		throughput := float64(stats.bytes-last.bytes) * 8 / interval.Seconds() / 1e6
+		format, program, preview := stats.format, stats.program, stats.preview
+		last.video, last.audio, last.metadata, last.bytes = stats.video, stats.audio, stats.metadata, stats.bytes
+		stats.mu.Unlock()
+
+		tally := "-"
+		if program {
+			tally = "PGM"
+		} else if preview {
+			tally = "PVW"
+		}
+
+		fmt.Printf("%s  video %3d (drop %d)  audio %3d (drop %d)  meta %d  queue %d/%d/%d  %s  %s %.1f Mbit/s  levels %s  tally %s\n",
+			time.Now().Format("15:04:05"),
+			video, dropped.VideoFrames-lastDropped.VideoFrames,
+			audioFrames, dropped.AudioFrames-lastDropped.AudioFrames,
+			meta, queue.VideoFrames, queue.AudioFrames, queue.MetadataFrames,
+			format, throughputLabel, throughput, formatLevels(meter.Current()), tally)
+
+		lastDropped = dropped
+	}
+}
+
+// Capture frames until the context is done, counting them and measuring the audio. When the connection is lost, it
+// prints that it is disconnected, and keeps capturing, as the SDK reconnects when the source is back.
+func (s *monitorStats) capture(ctx context.Context, receiver *gondi.RecvInstance, meter *audio.Meter) {
+	for ctx.Err() == nil {
+		vf, af, mf := gondi.NewVideoFrameV2(), gondi.NewAudioFrameV2(), &gondi.MetadataFrame{}
+
+		frameType := receiver.CaptureV2(vf, af, mf, 100)
+		if frameType == gondi.FrameTypeError {
+			if s.setDisconnected(true) {
+				fmt.Printf("%s  disconnected\n", time.Now().Format("15:04:05"))
+			}
+			// The capture fails right away while disconnected
+			select {
+			case <-ctx.Done():
+			case <-time.After(100 * time.Millisecond):
+			}
+			continue
+		}
+		if frameType != gondi.FrameTypeNone && s.setDisconnected(false) {
+			fmt.Printf("%s  connected\n", time.Now().Format("15:04:05"))
+		}
+
+		switch frameType {
+		case gondi.FrameTypeVideo:
+			s.mu.Lock()
+			s.video++
+			s.bytes += videoBytes(vf)
+			s.format = fmt.Sprintf("%dx%d %s %s", vf.Xres, vf.Yres, vf.FourCC, vf.GetFrameRate())
+			s.mu.Unlock()
+			receiver.FreeVideoV2(vf)
+		case gondi.FrameTypeAudio:
+			meter.Process(af)
+			s.mu.Lock()
+			s.audio++
+			s.bytes += int64(af.NumSamples) * int64(af.NumChannels) * 4
+			s.mu.Unlock()
+			receiver.FreeAudioV2(af)
+		case gondi.FrameTypeMetadata:
+			data := mf.GetData()
+			receiver.FreeMetadata(mf)
+
+			echo, _ := metadata.Decode(data)
+			s.mu.Lock()
+			s.metadata++
+			s.bytes += int64(len(data))
+			if tally, ok := echo.(*metadata.TallyEcho); ok {
+				s.program, s.preview = tally.OnProgram, tally.OnPreview
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Set whether the connection is lost, returns true if that changed.
+func (s *monitorStats) setDisconnected(disconnected bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := s.disconnected != disconnected
+	s.disconnected = disconnected
+	return changed
+}
+
+// Get the size of the video data of a frame. For compressed video, which has a FourCC without a known line stride,
+// the SDK puts the size of the compressed data where the line stride is for uncompressed video.
+func videoBytes(vf *gondi.VideoFrameV2) int64 {
+	if vf.FourCC.LineStride(vf.Xres) == 0 {
+		return int64(vf.LineStride)
+	}
+	return int64(vf.DataSize())
+}
+
+// Format the peak level of each channel in dBFS.
+func formatLevels(levels audio.Levels) string {
+	if len(levels.Channels) == 0 {
+		return "-"
+	}
+
+	parts := make([]string, len(levels.Channels))
+	for i, channel := range levels.Channels {
+		if math.IsInf(channel.Peak, -1) {
+			parts[i] = "-inf"
+		} else {
+			parts[i] = fmt.Sprintf("%.1f", channel.Peak)
+		}
+	}
+	return strings.Join(parts, " ") + " dBFS"
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Test patterns drawn once into the base frame. The moving and time dependent parts are drawn on top of it for
+// every frame, see testSignal.
+var patterns = map[string]func(c *canvas){
+	"bars":      drawBars,
+	"ramp":      drawRamp,
+	"zoneplate": drawZonePlate,
+	"box":       drawBoxBackground,
+	"black":     func(c *canvas) { c.fill(0, 0, c.width, c.height, black) },
+}
+
+func patternNames() string {
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func parsePattern(name string) (func(c *canvas), error) {
+	pattern, ok := patterns[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown pattern %q, use one of %s", name, patternNames())
+	}
+	return pattern, nil
+}
+
+// SMPTE color bars: 75% bars, the reverse blue bars, and the -I, white, +Q and PLUGE row.
+func drawBars(c *canvas) {
+	bars := []rgb{{191, 191, 191}, {191, 191, 0}, {0, 191, 191}, {0, 191, 0}, {191, 0, 191}, {191, 0, 0}, {0, 0, 191}}
+	reverse := []rgb{{0, 0, 191}, black, {191, 0, 191}, black, {0, 191, 191}, black, {191, 191, 191}}
+
+	top := c.height * 2 / 3
+	middle := c.height * 3 / 4
+	for i := range bars {
+		x0, x1 := c.width*i/7, c.width*(i+1)/7
+		c.fill(x0, 0, x1-x0, top, bars[i])
+		c.fill(x0, top, x1-x0, middle-top, reverse[i])
+	}
+
+	// The bottom row is divided in sixths of the width of four bars, followed by the PLUGE under the red bar
+	bottom := []struct {
+		width float64
+		color rgb
+	}{
+		{5.0 / 4, rgb{0, 33, 76}}, {5.0 / 4, white}, {5.0 / 4, rgb{50, 0, 106}}, {5.0 / 4, black},
+		{1.0 / 3, rgb{0, 0, 0}}, {1.0 / 3, black}, {1.0 / 3, rgb{10, 10, 10}}, {1, black},
+	}
+	x := 0.0
+	for _, part := range bottom {
+		x0, x1 := int(x*float64(c.width)/7), int((x+part.width)*float64(c.width)/7)
+		c.fill(x0, middle, x1-x0, c.height-middle, part.color)
+		x += part.width
+	}
+}
+
+// A horizontal luma ramp in the top half, and red, green and blue ramps in the bottom half.
+func drawRamp(c *canvas) {
+	for x := 0; x < c.width; x += 2 {
+		v := uint8(x * 255 / max(c.width-1, 1))
+		c.fill(x, 0, 2, c.height/2, rgb{v, v, v})
+
+		third := (c.height - c.height/2) / 3
+		c.fill(x, c.height/2, 2, third, rgb{v, 0, 0})
+		c.fill(x, c.height/2+third, 2, third, rgb{0, v, 0})
+		c.fill(x, c.height/2+2*third, 2, c.height-c.height/2-2*third, rgb{0, 0, v})
+	}
+}
+
+// A circular zone plate, where the frequency increases from the center and reaches half the sample rate
+// at the left and right edges. Aliasing shows up as additional circles.
+func drawZonePlate(c *canvas) {
+	cx, cy := float64(c.width)/2, float64(c.height)/2
+	for y := 0; y < c.height; y++ {
+		for x := 0; x < c.width; x++ {
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			phase := math.Pi * (dx*dx + dy*dy) / (2 * cx)
+			c.setGrey(x, y, uint8(127.5+127.5*math.Cos(phase)))
+		}
+	}
+}
+
+// A dark background with a centre line, for the moving box.
+func drawBoxBackground(c *canvas) {
+	c.fill(0, 0, c.width, c.height, rgb{32, 32, 32})
+	c.fill(c.width/2-1, 0, 2, c.height, rgb{128, 128, 128})
+}
+
+// Draw the moving box for a frame. It crosses the frame once per second, so the delay between two outputs
+// can be read from the offset between the boxes.
+func drawBox(c *canvas, frame int64, framesPerSecond float64) {
+	size := c.height / 6
+	travel := float64(c.width - size)
+	position := math.Mod(float64(frame), framesPerSecond) / framesPerSecond
+	c.fill(int(position*travel), (c.height-size)/2, size, size, white)
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitfocus/gondi"
+)
+
+var playCommand = &command{
+	name:  "play",
+	usage: "[options] <video.y4m | video.raw | recording.json>",
+	short: "Send a video file, with WAV audio and metadata, as an NDI source",
+	run:   runPlay,
+}
+
+// A video file with its audio, metadata and timecodes, ready to be sent.
+type playback struct {
+	video     *videoReader
+	audio     *wavReader
+	metadata  map[int64][]recordingMetadata
+	timecodes []gondi.Timecode
+}
+
+func runPlay(ctx context.Context, cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	name := fs.String("name", "gondi play", "name of the sender")
+	groups := fs.String("groups", "", "comma separated list of groups to send in")
+	sidecar := fs.String("sidecar", "", "sidecar JSON written by record, found next to the video file when empty")
+	audioPath := fs.String("audio", "", "WAV or RF64 file to play, taken from the sidecar when empty")
+	metadataPath := fs.String("metadata", "", "metadata JSONL file to replay, taken from the sidecar when empty")
+	loop := fs.Bool("loop", false, "play the files in a loop")
+	synthesize := fs.Bool("synthesize", false, "synthesize timecodes instead of using the ones from the sidecar")
+	rate := fs.String("rate", "", "frame rate, overriding the one from the file")
+	size := fs.String("size", "", "resolution of a raw video file without a sidecar")
+	fourcc := fs.String("fourcc", "UYVY", "FourCC of a raw video file without a sidecar")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	videoPath := fs.Arg(0)
+	if strings.HasSuffix(videoPath, ".json") {
+		*sidecar, videoPath = videoPath, ""
+	} else if *sidecar == "" {
+		candidate := strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + ".json"
+		if _, err := os.Stat(candidate); err == nil {
+			*sidecar = candidate
+		}
+	}
+
+	var rec *recording
+	if *sidecar != "" {
+		var err error
+		if rec, err = readRecording(*sidecar); err != nil {
+			return err
+		}
+		dir := filepath.Dir(*sidecar)
+		if rec.Video == nil {
+			return fmt.Errorf("%s has no video", *sidecar)
+		}
+		if videoPath == "" {
+			videoPath = filepath.Join(dir, rec.Video.File)
+		}
+		if *audioPath == "" && rec.Audio != nil {
+			*audioPath = filepath.Join(dir, rec.Audio.File)
+		}
+		if *metadataPath == "" && rec.Metadata != "" {
+			*metadataPath = filepath.Join(dir, rec.Metadata)
+		}
+	}
+
+	p := &playback{}
+	defer p.close()
+
+	var err error
+	if strings.HasSuffix(videoPath, ".y4m") {
+		p.video, err = openY4M(videoPath)
+	} else {
+		p.video, err = openRawFromFlags(videoPath, rec, *size, *fourcc, *rate)
+	}
+	if err != nil {
+		return err
+	}
+	if *rate != "" {
+		frameRate, err := gondi.ParseFrameRate(*rate)
+		if err != nil {
+			return err
+		}
+		p.video.format.SetFrameRate(frameRate)
+	}
+
+	if *audioPath != "" {
+		if p.audio, err = openWAV(*audioPath); err != nil {
+			return err
+		}
+	}
+	if *metadataPath != "" {
+		if p.metadata, err = readMetadata(*metadataPath); err != nil {
+			return err
+		}
+	}
+	if rec != nil && rec.Frames != "" && !*synthesize {
+		if p.timecodes, err = readFrameTimecodes(filepath.Join(filepath.Dir(*sidecar), rec.Frames)); err != nil {
+			return err
+		}
+	}
+
+	sender, err := gondi.NewSendInstanceWithOptions(gondi.SendOptions{Name: *name, Groups: *groups, ClockVideo: true})
+	if err != nil {
+		return err
+	}
+	defer sender.Close()
+
+	format := p.video.format
+	fmt.Fprintf(os.Stderr, "Playing %s as %s: %dx%d %s at %s\n", videoPath, *name, format.Xres, format.Yres, format.FourCC, format.GetFrameRate())
+
+	return p.play(ctx, sender, *loop)
+}
+
+// Open a raw video file, with the format from the sidecar, or from the flags.
+func openRawFromFlags(path string, rec *recording, size, fourcc, rate string) (*videoReader, error) {
+	format := gondi.NewVideoFrameV2()
+	var err error
+
+	if rec != nil {
+		if rec.Video.Container != "raw" {
+			return openY4M(path)
+		}
+		format.Xres, format.Yres = rec.Video.Width, rec.Video.Height
+		format.SetFrameRate(rec.Video.FrameRate)
+		format.PictureAspectRatio = rec.Video.AspectRatio
+		for frameFormat, name := range frameFormats {
+			if name == rec.Video.FrameFormat {
+				format.FrameFormatType = frameFormat
+			}
+		}
+		fourcc = rec.Video.FourCC
+	} else {
+		if size == "" || rate == "" {
+			return nil, errors.New("raw video without a sidecar needs -size and -rate")
+		}
+		if format.Xres, format.Yres, err = parseSize(size); err != nil {
+			return nil, err
+		}
+	}
+
+	if format.FourCC, err = parseFourCC(fourcc); err != nil {
+		return nil, err
+	}
+	return openRaw(path, format)
+}
+
+// Read a metadata JSONL file written by record, indexed by frame number.
+func readMetadata(path string) (map[int64][]recordingMetadata, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	metadata := make(map[int64][]recordingMetadata)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var m recordingMetadata
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		metadata[m.Frame] = append(metadata[m.Frame], m)
+	}
+
+	return metadata, scanner.Err()
+}
+
+// Get the timecode of a frame. Recorded timecodes continue after the last frame when looping, and without
+// recorded timecodes they are synthesized from the start time.
+func (p *playback) timecode(start gondi.Timecode, frame int64, index int64, loops int64) gondi.Timecode {
+	rate := p.video.format.GetFrameRate()
+	if len(p.timecodes) == 0 || index >= int64(len(p.timecodes)) {
+		return start.AddFrames(frame, rate.N, rate.D)
+	}
+
+	first, last := p.timecodes[0], p.timecodes[len(p.timecodes)-1]
+	span := last.Sub(first) + rate.FrameDuration()
+	return p.timecodes[index].Add(span * time.Duration(loops))
+}
+
+func (p *playback) play(ctx context.Context, sender *gondi.SendInstance, loop bool) error {
+	format := p.video.format
+	rate := format.GetFrameRate()
+
+	pool, err := sender.NewAsyncVideoSender(format, 2)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	var (
+		audioFrame *gondi.AudioFrameV2
+		samples    []float32
+		maxSamples int32
+	)
+	if p.audio != nil {
+		for _, n := range rate.AudioCadence(int32(p.audio.sampleRate)) {
+			maxSamples = max(maxSamples, n)
+		}
+		samples = make([]float32, int(maxSamples)*p.audio.channels)
+		audioFrame = gondi.NewAudioFrameV2()
+		audioFrame.SampleRate = int32(p.audio.sampleRate)
+		audioFrame.NumChannels = int32(p.audio.channels)
+		audioFrame.ChannelStride = maxSamples * 4
+		audioFrame.Data = &samples[0]
+	}
+
+	start := gondi.TimecodeNow()
+	start = gondi.TimecodeFromFrames(start.Frames(rate.N, rate.D), rate.N, rate.D)
+
+	var index, loops int64
+	for frame := int64(0); ctx.Err() == nil; frame++ {
+		video, err := pool.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = p.video.read(pool.Buffer(video))
+		if err == io.EOF && loop && index > 0 {
+			index = 0
+			loops++
+			if err = p.rewind(); err == nil {
+				err = p.video.read(pool.Buffer(video))
+			}
+		}
+		if err != nil {
+			pool.Release(video)
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		tc := p.timecode(start, frame, index, loops)
+		video.SetTimecode(tc)
+
+		for _, m := range p.metadata[index] {
+			if m.Video {
+				video.Metadata = gondi.NewMetadataFrame(m.Data).Data
+				continue
+			}
+			metadata := gondi.NewMetadataFrame(m.Data)
+			metadata.SetTimecode(tc)
+			if err := sender.SendMetadataFrame(metadata); err != nil {
+				return err
+			}
+		}
+
+		if err := pool.Submit(video); err != nil {
+			return err
+		}
+
+		if audioFrame != nil {
+			audioFrame.NumSamples = rate.SamplesPerFrame(audioFrame.SampleRate, frame)
+			if _, err := p.audio.read(samples, int(maxSamples), int(audioFrame.NumSamples)); err != nil {
+				return err
+			}
+			audioFrame.SetTimecode(tc)
+			if err := sender.SendAudioFrame(audioFrame); err != nil {
+				return err
+			}
+		}
+
+		index++
+	}
+
+	return ctx.Err()
+}
+
+// Go back to the start of the video and audio files.
+func (p *playback) rewind() error {
+	if err := p.video.rewind(); err != nil {
+		return err
+	}
+	if p.audio != nil {
+		return p.audio.rewind()
+	}
+	return nil
+}
+
+func (p *playback) close() {
+	if p.video != nil {
+		p.video.close()
+	}
+	if p.audio != nil {
+		p.audio.close()
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bitfocus/gondi"
+)
+
+func TestY4MRoundTrip(t *testing.T) {
+	frame := gondi.NewVideoFrameV2()
+	frame.Xres, frame.Yres, frame.FourCC = 4, 2, gondi.FourCCTypeUYVY
+	frame.SetFrameRate(gondi.FrameRate2997)
+	data := []byte{1, 2, 3, 4, 5, 6, 7, 8, 11, 12, 13, 14, 15, 16, 17, 18}
+	frame.Data = &data[0]
+
+	path := filepath.Join(t.TempDir(), "video.y4m")
+	w, err := createVideo(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.write(frame)
+	w.write(frame)
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := openY4M(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.close()
+	if r.format.Xres != 4 || r.format.Yres != 2 || r.format.GetFrameRate() != gondi.FrameRate2997 {
+		t.Errorf("read format %dx%d at %s", r.format.Xres, r.format.Yres, r.format.GetFrameRate())
+	}
+
+	got := make([]byte, len(data))
+	for i := 0; i < 2; i++ {
+		if err := r.read(got); err != nil || string(got) != string(data) {
+			t.Fatalf("frame %d is %v, %v", i, got, err)
+		}
+	}
+	if err := r.read(got); err != io.EOF {
+		t.Errorf("read after the last frame returned %v", err)
+	}
+
+	r.rewind()
+	if err := r.read(got); err != nil || string(got) != string(data) {
+		t.Errorf("frame after rewind is %v, %v", got, err)
+	}
+}
+
+func TestWAVRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audio.wav")
+	w, _ := createWAV(path, 48000, 2, false)
+	w.write([]float32{0.5, -0.5, 0.25, -0.25, 0.125, -0.125})
+	w.close()
+
+	r, err := openWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.close()
+	if r.sampleRate != 48000 || r.channels != 2 {
+		t.Errorf("read format %d Hz, %d channels", r.sampleRate, r.channels)
+	}
+
+	// Planar with a stride of 4, the samples after the end of the file are silent
+	planar := make([]float32, 8)
+	n, err := r.read(planar, 4, 4)
+	want := []float32{0.5, 0.25, 0.125, 0, -0.5, -0.25, -0.125, 0}
+	if err != nil || n != 3 {
+		t.Fatalf("read %d samples, %v", n, err)
+	}
+	for i := range want {
+		if planar[i] != want[i] {
+			t.Fatalf("planar samples are %v, want %v", planar, want)
+		}
+	}
+	if n, _ := r.read(planar, 4, 4); n != 0 {
+		t.Errorf("read %d samples after the end", n)
+	}
+}
+
+func TestPlaybackTimecode(t *testing.T) {
+	format := gondi.NewVideoFrameV2()
+	format.SetFrameRate(gondi.FrameRate25)
+	base := gondi.TimecodeFromDuration(10 * time.Hour)
+	p := &playback{
+		video:     &videoReader{format: format},
+		timecodes: []gondi.Timecode{base, base.Add(40 * time.Millisecond), base.Add(80 * time.Millisecond)},
+	}
+
+	// The second loop continues after the last recorded frame
+	if tc := p.timecode(0, 4, 1, 1); tc.Sub(base) != 160*time.Millisecond {
+		t.Errorf("timecode in the second loop is %v after the start", tc.Sub(base))
+	}
+
+	p.timecodes = nil
+	if tc := p.timecode(base, 5, 5, 0); tc.Sub(base) != 200*time.Millisecond {
+		t.Errorf("synthesized timecode is %v after the start", tc.Sub(base))
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bitfocus/gondi"
+)
+
+var recordCommand = &command{
+	name:  "record",
+	usage: "[options] <source>",
+	short: "Record the video, audio and metadata of a source to files",
+	run:   runRecord,
+}
+
+// Files written while recording, see recording for the sidecar describing them.
+type recorder struct {
+	base     string
+	rec      recording
+	video    *videoWriter
+	audio    *wavWriter
+	metadata *json.Encoder
+	metaFile *os.File
+	timing   *json.Encoder
+	timeFile *os.File
+	frames   int64
+}
+
+func runRecord(ctx context.Context, cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	var find finderFlags
+	find.registerWithTimeout(fs, 5*time.Second)
+	output := fs.String("o", "", "output path without extension, derived from the source name when empty")
+	videoFormat := fs.String("video", "y4m", "video file format: y4m, raw or none")
+	audioFormat := fs.String("audio", "wav", "audio file format: wav, rf64 or none")
+	metadata := fs.Bool("metadata", true, "record metadata to a JSONL file")
+	duration := fs.Duration("duration", 0, "stop after recording this long, 0 records until interrupted")
+	frames := fs.Int64("frames", 0, "stop after this many video frames, 0 records until interrupted")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	if *videoFormat != "y4m" && *videoFormat != "raw" && *videoFormat != "none" {
+		return fmt.Errorf("invalid video format %q", *videoFormat)
+	}
+	if *audioFormat != "wav" && *audioFormat != "rf64" && *audioFormat != "none" {
+		return fmt.Errorf("invalid audio format %q", *audioFormat)
+	}
+
+	source, err := find.find(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		*output = fileName(source.Name()) + "-" + time.Now().Format("20060102-150405")
+	}
+
+	receiver, err := gondi.NewRecvInstanceWithOptions(
+		gondi.WithRecvSource(source),
+		gondi.WithRecvName("gondi record"),
+	)
+	if err != nil {
+		return err
+	}
+	defer receiver.Close()
+
+	r := &recorder{base: *output, rec: recording{Source: source.Name()}}
+	if *metadata {
+		if err := r.openMetadata(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Recording %s to %s.*, interrupt to stop\n", source.Name(), *output)
+
+	var deadline time.Time
+	for ctx.Err() == nil {
+		if *frames > 0 && r.frames >= *frames {
+			break
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			break
+		}
+
+		vf, af, mf := gondi.NewVideoFrameV2(), gondi.NewAudioFrameV2(), &gondi.MetadataFrame{}
+		if *videoFormat == "none" {
+			vf = nil
+		}
+		if *audioFormat == "none" {
+			af = nil
+		}
+
+		frameType := receiver.CaptureV2(vf, af, mf, 100)
+		if frameType == gondi.FrameTypeError {
+			err = fmt.Errorf("connection to %q failed", source.Name())
+			break
+		}
+		if frameType != gondi.FrameTypeNone && frameType != gondi.FrameTypeStatusChange && deadline.IsZero() {
+			r.rec.Started = time.Now()
+			if *duration > 0 {
+				deadline = r.rec.Started.Add(*duration)
+			}
+		}
+
+		switch frameType {
+		case gondi.FrameTypeVideo:
+			err = r.writeVideo(vf, *videoFormat)
+			receiver.FreeVideoV2(vf)
+		case gondi.FrameTypeAudio:
+			err = r.writeAudio(af, *audioFormat == "rf64")
+			receiver.FreeAudioV2(af)
+		case gondi.FrameTypeMetadata:
+			err = r.writeMetadata(mf.GetData(), mf.GetTimecode(), false)
+			receiver.FreeMetadata(mf)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if closeErr := r.close(); err == nil {
+		err = closeErr
+	}
+
+	total, dropped := receiver.GetPerformance()
+	w := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "\tvideo\taudio\tmetadata\t\n")
+	fmt.Fprintf(w, "received\t%d\t%d\t%d\t\n", total.VideoFrames, total.AudioFrames, total.MetadataFrames)
+	fmt.Fprintf(w, "dropped\t%d\t%d\t%d\t\n", dropped.VideoFrames, dropped.AudioFrames, dropped.MetadataFrames)
+	fmt.Fprintf(w, "recorded\t%d\t\t\t\n", r.frames)
+	w.Flush()
+
+	return err
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Turn a source name into something that can be used as a file name.
+func fileName(name string) string {
+	return strings.Trim(unsafeFileChars.ReplaceAllString(name, "_"), "_")
+}
+
+func (r *recorder) openMetadata() error {
+	path := r.base + ".meta.jsonl"
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	r.metaFile = file
+	r.metadata = json.NewEncoder(file)
+	r.rec.Metadata = filepath.Base(path)
+	return nil
+}
+
+func (r *recorder) writeVideo(frame *gondi.VideoFrameV2, format string) error {
+	if r.video == nil {
+		path := r.base + "." + format
+		video, err := createVideo(path, format == "y4m")
+		if err != nil {
+			return err
+		}
+		r.video = video
+
+		timingPath := r.base + ".frames.jsonl"
+		if r.timeFile, err = os.Create(timingPath); err != nil {
+			return err
+		}
+		r.timing = json.NewEncoder(r.timeFile)
+		r.rec.Frames = filepath.Base(timingPath)
+
+		r.rec.Video = &recordingVideo{
+			File:        filepath.Base(path),
+			Container:   format,
+			Width:       frame.Xres,
+			Height:      frame.Yres,
+			FourCC:      frame.FourCC.String(),
+			FrameRate:   frame.GetFrameRate(),
+			AspectRatio: frame.PictureAspectRatio,
+			FrameFormat: frameFormats[frame.FrameFormatType],
+		}
+	}
+
+	if err := r.video.write(frame); err != nil {
+		return err
+	}
+
+	var timestamp gondi.Timecode
+	if ts, ok := frame.GetTimestamp(); ok {
+		timestamp = ts
+	}
+	if err := r.timing.Encode(recordingFrame{Timecode: frame.GetTimecode(), Timestamp: timestamp}); err != nil {
+		return err
+	}
+
+	if frame.Metadata != nil {
+		metadata := gondi.MetadataFrame{Data: frame.Metadata}
+		if err := r.writeMetadata(metadata.GetData(), frame.GetTimecode(), true); err != nil {
+			return err
+		}
+	}
+
+	r.frames++
+	return nil
+}
+
+func (r *recorder) writeAudio(frame *gondi.AudioFrameV2, rf64 bool) error {
+	if r.audio == nil {
+		path := r.base + ".wav"
+		audio, err := createWAV(path, int(frame.SampleRate), int(frame.NumChannels), rf64)
+		if err != nil {
+			return err
+		}
+		r.audio = audio
+		container := "wav"
+		if rf64 {
+			container = "rf64"
+		}
+		r.rec.Audio = &recordingAudio{File: filepath.Base(path), Container: container, SampleRate: frame.SampleRate, Channels: frame.NumChannels}
+	}
+
+	if frame.SampleRate != r.rec.Audio.SampleRate || frame.NumChannels != r.rec.Audio.Channels {
+		return fmt.Errorf("audio format changed from %d Hz %d channels to %d Hz %d channels during recording",
+			r.rec.Audio.SampleRate, r.rec.Audio.Channels, frame.SampleRate, frame.NumChannels)
+	}
+
+	r.rec.Audio.Samples += int64(frame.NumSamples)
+	return r.audio.write(frame.GetInterleavedArray())
+}
+
+func (r *recorder) writeMetadata(data string, timecode gondi.Timecode, video bool) error {
+	if r.metadata == nil {
+		return nil
+	}
+	return r.metadata.Encode(recordingMetadata{Frame: r.frames, Time: time.Now(), Timecode: timecode, Video: video, Data: data})
+}
+
+// Close the files and write the sidecar.
+func (r *recorder) close() error {
+	var errs []error
+	if r.video != nil {
+		errs = append(errs, r.video.close())
+	}
+	if r.audio != nil {
+		errs = append(errs, r.audio.close())
+	}
+	if r.timeFile != nil {
+		errs = append(errs, r.timeFile.Close())
+	}
+	if r.metaFile != nil {
+		errs = append(errs, r.metaFile.Close())
+	}
+	errs = append(errs, writeRecording(r.base+".json", &r.rec))
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitfocus/gondi"
+)
+
+func TestWAVWriter(t *testing.T) {
+	for _, rf64 := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "audio.wav")
+		w, err := createWAV(path, 48000, 2, rf64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.write([]float32{0.5, -0.5, 0.25, -0.25, 0, 0})
+		if err := w.close(); err != nil {
+			t.Fatal(err)
+		}
+
+		data, _ := os.ReadFile(path)
+		if len(data) != wavHeaderSize+24 {
+			t.Fatalf("file is %d bytes", len(data))
+		}
+
+		// The chunk layout is fixed, so the sizes can be patched in place when the file is closed
+		if string(data[48:52]) != "fmt " || string(data[74:78]) != "fact" || string(data[86:90]) != "data" {
+			t.Errorf("chunks are at the wrong offsets: %q", data[:wavHeaderSize])
+		}
+		r, err := openWAV(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.file.Close()
+		if r.sampleRate != 48000 || r.channels != 2 || r.dataStart != wavHeaderSize || r.dataSize != 24 {
+			t.Errorf("read back %d Hz, %d channels, %d bytes at %d", r.sampleRate, r.channels, r.dataSize, r.dataStart)
+		}
+
+		if rf64 {
+			if string(data[0:4]) != "RF64" || string(data[wavJunk:wavJunk+4]) != "ds64" ||
+				binary.LittleEndian.Uint64(data[wavDS64+8:]) != 24 || binary.LittleEndian.Uint64(data[wavDS64+16:]) != 3 {
+				t.Error("invalid RF64 header")
+			}
+			continue
+		}
+		if string(data[0:4]) != "RIFF" || binary.LittleEndian.Uint32(data[4:]) != uint32(len(data)-8) ||
+			binary.LittleEndian.Uint32(data[wavDataSize:]) != 24 || binary.LittleEndian.Uint32(data[wavFactSamples:]) != 3 {
+			t.Error("invalid WAV header")
+		}
+	}
+}
+
+func TestY4MWriter(t *testing.T) {
+	frame := gondi.NewVideoFrameV2()
+	frame.Xres, frame.Yres, frame.FourCC = 4, 2, gondi.FourCCTypeUYVY
+	frame.LineStride = 12 // padded
+	data := []byte{
+		1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 0, 0,
+		11, 12, 13, 14, 15, 16, 17, 18, 0, 0, 0, 0,
+	}
+	frame.Data = &data[0]
+
+	header, err := y4mHeader(frame)
+	if err != nil || header != "YUV4MPEG2 W4 H2 F25:1 Ip A1:1 C422\n" {
+		t.Errorf("header is %q, %v", header, err)
+	}
+
+	w := &videoWriter{}
+	want := []byte{2, 4, 6, 8, 12, 14, 16, 18, 1, 5, 11, 15, 3, 7, 13, 17}
+	if planar := w.planar(frame); string(planar) != string(want) {
+		t.Errorf("planar data is %v, want %v", planar, want)
+	}
+
+	want = []byte{1, 2, 3, 4, 5, 6, 7, 8, 11, 12, 13, 14, 15, 16, 17, 18}
+	if packed := w.packed(frame); string(packed) != string(want) {
+		t.Errorf("packed data is %v, want %v", packed, want)
+	}
+}
+
+func TestRecordFrameTimecodes(t *testing.T) {
+	frame := gondi.NewVideoFrameV2()
+	frame.Xres, frame.Yres, frame.FourCC = 2, 2, gondi.FourCCTypeUYVY
+	frame.LineStride = 4
+	frame.FrameFormatType = gondi.FrameFormatField0
+	data := make([]byte, 8)
+	frame.Data = &data[0]
+
+	// Frame timecodes are written to their own file as the frames are recorded
+	r := &recorder{base: filepath.Join(t.TempDir(), "rec")}
+	for i := int64(0); i < 3; i++ {
+		frame.SetTimecode(gondi.TimecodeFromFrames(i, 25, 1))
+		if err := r.writeVideo(frame, "y4m"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := readRecording(r.base + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Frames != "rec.frames.jsonl" || rec.Video.FrameFormat != "field0" {
+		t.Errorf("sidecar has frames %q and frame format %q", rec.Frames, rec.Video.FrameFormat)
+	}
+	timecodes, err := readFrameTimecodes(filepath.Join(filepath.Dir(r.base), rec.Frames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timecodes) != 3 || timecodes[2] != gondi.TimecodeFromFrames(2, 25, 1) {
+		t.Errorf("read back timecodes %v", timecodes)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bitfocus/gondi"
+)
+
+// The sidecar JSON written by record and read by play. File names are relative to the sidecar.
+type recording struct {
+	Source   string          `json:"source"`
+	Started  time.Time       `json:"started"`
+	Video    *recordingVideo `json:"video,omitempty"`
+	Audio    *recordingAudio `json:"audio,omitempty"`
+	Metadata string          `json:"metadata,omitempty"`
+	Frames   string          `json:"frames,omitempty"`
+}
+
+type recordingVideo struct {
+	File        string          `json:"file"`
+	Container   string          `json:"container"`
+	Width       int32           `json:"width"`
+	Height      int32           `json:"height"`
+	FourCC      string          `json:"fourcc"`
+	FrameRate   gondi.FrameRate `json:"frame_rate"`
+	AspectRatio float32         `json:"aspect_ratio,omitempty"`
+	FrameFormat string          `json:"frame_format"`
+}
+
+type recordingAudio struct {
+	File       string `json:"file"`
+	Container  string `json:"container"`
+	SampleRate int32  `json:"sample_rate"`
+	Channels   int32  `json:"channels"`
+	Samples    int64  `json:"samples"`
+}
+
+// A line of the frames JSONL file, with the timecode and timestamp of a recorded video frame, in 100ns units.
+// The timestamp is omitted when the sender did not provide one. The lines are written as the frames are recorded,
+// so long recordings do not keep them in memory.
+type recordingFrame struct {
+	Timecode  gondi.Timecode `json:"timecode"`
+	Timestamp gondi.Timecode `json:"timestamp,omitempty"`
+}
+
+// A line of the metadata JSONL file. Frame is the number of video frames recorded before the metadata arrived,
+// and Video is true for metadata attached to that video frame rather than sent on its own.
+type recordingMetadata struct {
+	Frame    int64          `json:"frame"`
+	Time     time.Time      `json:"time"`
+	Timecode gondi.Timecode `json:"timecode"`
+	Video    bool           `json:"video,omitempty"`
+	Data     string         `json:"data"`
+}
+
+// The names of the frame formats, as written to the sidecar and printed by inspect.
+var frameFormats = map[gondi.FrameFormat]string{
+	gondi.FrameFormatProgressive: "progressive",
+	gondi.FrameFormatInterleaved: "interleaved",
+	gondi.FrameFormatField0:      "field0",
+	gondi.FrameFormatField1:      "field1",
+}
+
+func readRecording(path string) (*recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rec recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// Read the timecodes of the frames JSONL file written by record.
+func readFrameTimecodes(path string) ([]gondi.Timecode, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var timecodes []gondi.Timecode
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var frame recordingFrame
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		timecodes = append(timecodes, frame.Timecode)
+	}
+
+	return timecodes, scanner.Err()
+}
+
+func writeRecording(path string, rec *recording) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
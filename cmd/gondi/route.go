@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bitfocus/gondi"
+)
+
+var routeCommand = &command{
+	name:  "route",
+	usage: "[options] <output-name> <source>",
+	short: "Create a routed output, and change its source with lines read from stdin",
+	run:   runRoute,
+}
+
+func runRoute(ctx context.Context, cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	var find finderFlags
+	find.registerWithTimeout(fs, 5*time.Second)
+	outputGroups := fs.String("output-groups", "", "comma separated list of groups to create the output in")
+	follow := fs.Bool("follow", true, "route again when the source shows up with a new address")
+	if err := parseArgs(fs, args, 2); err != nil {
+		return err
+	}
+
+	finder, err := find.open()
+	if err != nil {
+		return err
+	}
+	defer finder.Close()
+
+	routing, err := gondi.NewRoutingInstance(fs.Arg(0), *outputGroups)
+	if err != nil {
+		return err
+	}
+	defer routing.Close()
+
+	if *follow {
+		routing.Follow(finder)
+	}
+
+	outputName := fs.Arg(0)
+	if source, err := routing.SourceName(); err == nil {
+		outputName = source.Name()
+	}
+
+	route := func(name string) error {
+		if name == "" {
+			return errors.New("empty source name, use clear to clear the route")
+		}
+		if name == "clear" {
+			if err := routing.Clear(); err != nil {
+				return err
+			}
+			fmt.Printf("%s cleared\n", outputName)
+			return nil
+		}
+
+		source, err := waitForSource(ctx, finder, name, find.timeout)
+		if err != nil {
+			return err
+		}
+		if err := routing.Change(source); err != nil {
+			return err
+		}
+		fmt.Printf("%s routed to %s (%s)\n", outputName, source.Name(), source.Address())
+		return nil
+	}
+
+	if err := route(fs.Arg(1)); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Enter a source name to route it, or clear, interrupt to stop")
+
+	// Stdin is read in the background, so an interrupt is not held up by a blocking read
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- strings.TrimSpace(scanner.Text())
+		}
+		close(lines)
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	connections := int32(-1)
+	current, _ := routing.Current()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-lines:
+			if !ok {
+				lines = nil
+				continue
+			}
+			// An accidental empty line must not take the output off air
+			if line == "" {
+				continue
+			}
+			if err := route(line); err != nil {
+				fmt.Fprintf(os.Stderr, "gondi route: %v\n", err)
+			}
+			current, _ = routing.Current()
+		case <-ticker.C:
+			if n := routing.GetNumberOfConnections(0); n != connections {
+				connections = n
+				fmt.Printf("%s has %d connections\n", outputName, n)
+			}
+			if source, ok := routing.Current(); ok && current != nil && source.Address() != current.Address() {
+				fmt.Printf("%s followed %s to %s\n", outputName, source.Name(), source.Address())
+				current = source
+			}
+		}
+	}
+}
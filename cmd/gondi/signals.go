@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// Audio test signals. They all run at the same level, see audioGenerator.
+var signals = []string{"tone", "noise", "ident", "sync", "none"}
+
+// Generates planar audio for the test signal, one video frame at a time.
+type audioGenerator struct {
+	kind       string
+	sampleRate int
+	channels   int
+	frequency  float64
+	amplitude  float64
+
+	position int64
+	pink     [][7]float64
+	random   *rand.Rand
+}
+
+func newAudioGenerator(kind string, sampleRate, channels int, frequency, levelDB float64) (*audioGenerator, error) {
+	valid := false
+	for _, signal := range signals {
+		valid = valid || signal == kind
+	}
+	if !valid {
+		return nil, fmt.Errorf("unknown audio signal %q, use one of %s", kind, strings.Join(signals, ", "))
+	}
+
+	return &audioGenerator{
+		kind:       kind,
+		sampleRate: sampleRate,
+		channels:   channels,
+		frequency:  frequency,
+		amplitude:  math.Pow(10, levelDB/20),
+		pink:       make([][7]float64, channels),
+		random:     rand.New(rand.NewSource(1)),
+	}, nil
+}
+
+// Fill samples for each channel, with the channels stride samples apart. The beep of the sync signal is
+// generated when beep is true, it should last exactly one video frame.
+func (g *audioGenerator) generate(data []float32, stride, samples int, beep bool) {
+	for i := 0; i < samples; i++ {
+		n := g.position + int64(i)
+		t := float64(n) / float64(g.sampleRate)
+		tone := g.amplitude * math.Sin(2*math.Pi*g.frequency*t)
+
+		for ch := 0; ch < g.channels; ch++ {
+			var v float64
+			switch g.kind {
+			case "tone":
+				v = tone
+			case "noise":
+				v = g.amplitude * g.pinkNoise(ch)
+			case "ident":
+				if !identGap(ch, math.Mod(t, 4)) {
+					v = tone
+				}
+			case "sync":
+				if beep {
+					v = tone
+				}
+			}
+			data[ch*stride+i] = float32(v)
+		}
+	}
+
+	g.position += int64(samples)
+}
+
+// The ident interrupts the tone on channel n with n+1 short gaps at the start of every four second cycle,
+// so channels can be told apart by counting the gaps. Channels after the twelfth repeat the pattern.
+func identGap(ch int, t float64) bool {
+	gaps := ch%12 + 1
+	for i := 0; i < gaps; i++ {
+		start := 0.2 + 0.3*float64(i)
+		if t >= start && t < start+0.15 {
+			return true
+		}
+	}
+	return false
+}
+
+// Pink noise using Paul Kellet's filter of white noise, scaled to roughly full scale peaks.
+func (g *audioGenerator) pinkNoise(ch int) float64 {
+	b := &g.pink[ch]
+	white := g.random.Float64()*2 - 1
+
+	b[0] = 0.99886*b[0] + white*0.0555179
+	b[1] = 0.99332*b[1] + white*0.0750759
+	b[2] = 0.96900*b[2] + white*0.1538520
+	b[3] = 0.86650*b[3] + white*0.3104856
+	b[4] = 0.55000*b[4] + white*0.5329522
+	b[5] = -0.7616*b[5] - white*0.0168980
+	pink := b[0] + b[1] + b[2] + b[3] + b[4] + b[5] + b[6] + white*0.5362
+	b[6] = white * 0.115926
+
+	return pink * 0.2
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bitfocus/gondi"
+)
+
+var sourcesCommand = &command{
+	name:  "sources",
+	usage: "[options]",
+	short: "List the NDI sources on the network",
+	run:   runSources,
+}
+
+var watchCommand = &command{
+	name:  "watch",
+	usage: "[options]",
+	short: "Print NDI sources as they appear, move and disappear",
+	run:   runWatch,
+}
+
+type sourceJSON struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+func runSources(ctx context.Context, cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	var find finderFlags
+	find.registerWithTimeout(fs, 3*time.Second)
+	asJSON := fs.Bool("json", false, "print the sources as a JSON array")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	finder, err := find.open()
+	if err != nil {
+		return err
+	}
+	defer finder.Close()
+
+	sources := settleSources(ctx, finder, find.timeout)
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Name() < sources[j].Name() })
+
+	if *asJSON {
+		list := make([]sourceJSON, len(sources))
+		for i, source := range sources {
+			list[i] = sourceJSON{source.Name(), source.Address()}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(list)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tADDRESS")
+	for _, source := range sources {
+		fmt.Fprintf(w, "%s\t%s\n", source.Name(), source.Address())
+	}
+	return w.Flush()
+}
+
+type sourceEvent struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Name    string    `json:"name"`
+	Address string    `json:"address,omitempty"`
+}
+
+// Compare two lists of sources by name, and return the events that turn the old list into the new one.
+func diffSources(old map[string]string, sources []*gondi.Source, now time.Time) (map[string]string, []sourceEvent) {
+	current := make(map[string]string, len(sources))
+	var events []sourceEvent
+
+	for _, source := range sources {
+		name, address := source.Name(), source.Address()
+		current[name] = address
+
+		previous, ok := old[name]
+		if !ok {
+			events = append(events, sourceEvent{now, "added", name, address})
+		} else if previous != address {
+			events = append(events, sourceEvent{now, "moved", name, address})
+		}
+	}
+	for name := range old {
+		if _, ok := current[name]; !ok {
+			events = append(events, sourceEvent{now, "removed", name, ""})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Name < events[j].Name })
+	return current, events
+}
+
+func runWatch(ctx context.Context, cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	var find finderFlags
+	find.register(fs)
+	asJSON := fs.Bool("json", false, "print one JSON object per event")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	finder, err := find.open()
+	if err != nil {
+		return err
+	}
+	defer finder.Close()
+
+	encoder := json.NewEncoder(os.Stdout)
+	known := map[string]string{}
+	for ctx.Err() == nil {
+		var events []sourceEvent
+		known, events = diffSources(known, finder.GetCurrentSources(), time.Now())
+
+		for _, event := range events {
+			if *asJSON {
+				encoder.Encode(event)
+				continue
+			}
+			symbol := map[string]string{"added": "+", "moved": "~", "removed": "-"}[event.Event]
+			fmt.Printf("%s %s %s %s\n", event.Time.Format("15:04:05.000"), symbol, event.Name, event.Address)
+		}
+
+		finder.WaitForSources(500)
+	}
+
+	return ctx.Err()
+}
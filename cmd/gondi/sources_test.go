@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitfocus/gondi"
+)
+
+func TestDiffSources(t *testing.T) {
+	now := time.Now()
+	known, events := diffSources(nil, []*gondi.Source{
+		gondi.NewSource("A (Camera 1)", "10.0.0.1:5961"),
+		gondi.NewSource("A (Camera 2)", "10.0.0.1:5962"),
+	}, now)
+	if len(events) != 2 || events[0].Event != "added" || events[1].Event != "added" {
+		t.Fatalf("unexpected events %v", events)
+	}
+
+	_, events = diffSources(known, []*gondi.Source{
+		gondi.NewSource("A (Camera 1)", "10.0.0.9:5961"),
+		gondi.NewSource("B (Camera 3)", "10.0.0.2:5961"),
+	}, now)
+	want := []string{"A (Camera 1) moved", "A (Camera 2) removed", "B (Camera 3) added"}
+	if len(events) != len(want) {
+		t.Fatalf("unexpected events %v", events)
+	}
+	for i, event := range events {
+		if event.Name+" "+event.Event != want[i] {
+			t.Errorf("event %d is %s %s, want %s", i, event.Name, event.Event, want[i])
+		}
+	}
+}
+
+func TestMatchSource(t *testing.T) {
+	sources := []*gondi.Source{
+		gondi.NewSource("A (Camera 1)", ""),
+		gondi.NewSource("A (Camera 10)", ""),
+		gondi.NewSource("B (Playout)", ""),
+	}
+
+	if source, err := matchSource(sources, "A (Camera 1)"); err != nil || source.Name() != "A (Camera 1)" {
+		t.Errorf("exact match returned %v, %v", source, err)
+	}
+	if source, err := matchSource(sources, "playout"); err != nil || source.Name() != "B (Playout)" {
+		t.Errorf("partial match returned %v, %v", source, err)
+	}
+	if _, err := matchSource(sources, "camera"); err == nil {
+		t.Error("ambiguous match did not fail")
+	}
+	if source, err := matchSource(sources, "missing"); source != nil || err != nil {
+		t.Errorf("missing source returned %v, %v", source, err)
+	}
+}
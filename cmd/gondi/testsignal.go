@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bitfocus/gondi"
+)
+
+var testSignalCommand = &command{
+	name:  "testsignal",
+	usage: "[options]",
+	short: "Send test patterns and audio test signals as one or more NDI sources",
+	run:   runTestSignal,
+}
+
+var fourCCs = map[string]gondi.FourCCType{
+	"UYVY": gondi.FourCCTypeUYVY,
+	"UYVA": gondi.FourCCTypeUYVA,
+	"BGRA": gondi.FourCCTypeBGRA,
+	"BGRX": gondi.FourCCTypeBGRX,
+}
+
+func parseFourCC(s string) (gondi.FourCCType, error) {
+	fourcc, ok := fourCCs[strings.ToUpper(s)]
+	if !ok {
+		return fourcc, fmt.Errorf("unsupported FourCC %q, use UYVY, UYVA, BGRA or BGRX", s)
+	}
+	return fourcc, nil
+}
+
+// Parse a resolution like 1920x1080. The width must be even, for the 4:2:2 formats.
+func parseSize(s string) (int32, int32, error) {
+	w, h, ok := strings.Cut(strings.ToLower(s), "x")
+	width, err1 := strconv.Atoi(w)
+	height, err2 := strconv.Atoi(h)
+	if !ok || err1 != nil || err2 != nil || width <= 0 || height <= 0 || width%2 != 0 {
+		return 0, 0, fmt.Errorf("invalid size %q, use an even width, like 1920x1080", s)
+	}
+	return int32(width), int32(height), nil
+}
+
+// Settings shared by all senders of the test signal.
+type testSignal struct {
+	format     *gondi.VideoFrameV2
+	rate       gondi.FrameRate
+	base       []byte
+	moving     bool
+	burnIn     bool
+	flash      bool
+	start      gondi.Timecode
+	audio      string
+	sampleRate int
+	channels   int
+	frequency  float64
+	level      float64
+}
+
+func runTestSignal(ctx context.Context, cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	var names stringList
+	fs.Var(&names, "name", "name of a sender, repeat to run several senders (default \"gondi test signal\")")
+	groups := fs.String("groups", "", "comma separated list of groups to send in")
+	size := fs.String("size", "1920x1080", "video resolution")
+	rate := fs.String("rate", "50", "frame rate, like 25, 29.97 or 60000/1001")
+	fourcc := fs.String("fourcc", "UYVY", "video FourCC: UYVY, UYVA, BGRA or BGRX")
+	pattern := fs.String("pattern", "bars", "video pattern: "+patternNames())
+	burnIn := fs.Bool("burnin", true, "burn in the timecode and frame counter")
+	audio := fs.String("audio", "tone", "audio signal: "+strings.Join(signals, ", ")+"; sync also flashes the video")
+	sampleRate := fs.Int("sample-rate", 48000, "audio sample rate")
+	channels := fs.Int("channels", 2, "number of audio channels")
+	frequency := fs.Float64("frequency", 1000, "tone frequency in Hz")
+	level := fs.Float64("level", -20, "audio level in dBFS")
+	start := fs.String("start", "now", "timecode of the first frame as HH:MM:SS:FF, or now for the time of day")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		names = stringList{"gondi test signal"}
+	}
+
+	signal := &testSignal{
+		audio:      *audio,
+		burnIn:     *burnIn,
+		flash:      *audio == "sync",
+		moving:     *pattern == "box",
+		sampleRate: *sampleRate,
+		channels:   *channels,
+		frequency:  *frequency,
+		level:      *level,
+	}
+
+	var err error
+	if signal.rate, err = gondi.ParseFrameRate(*rate); err != nil {
+		return err
+	}
+	signal.format = gondi.NewVideoFrameV2()
+	if signal.format.Xres, signal.format.Yres, err = parseSize(*size); err != nil {
+		return err
+	}
+	if signal.format.FourCC, err = parseFourCC(*fourcc); err != nil {
+		return err
+	}
+	signal.format.SetFrameRate(signal.rate)
+	signal.format.LineStride = signal.format.FourCC.LineStride(signal.format.Xres)
+
+	if *start == "now" {
+		signal.start = gondi.TimecodeNow()
+	} else if signal.start, err = gondi.ParseSMPTE(*start, signal.rate.N, signal.rate.D); err != nil {
+		return err
+	}
+	signal.start = gondi.TimecodeFromFrames(signal.start.Frames(signal.rate.N, signal.rate.D), signal.rate.N, signal.rate.D)
+
+	if _, err := newAudioGenerator(*audio, *sampleRate, *channels, *frequency, *level); err != nil {
+		return err
+	}
+	if *channels <= 0 || *sampleRate <= 0 {
+		return fmt.Errorf("invalid audio format")
+	}
+
+	// The static part of the pattern is drawn once, and copied into every frame
+	draw, err := parsePattern(*pattern)
+	if err != nil {
+		return err
+	}
+	signal.base = make([]byte, signal.format.DataSize())
+	signal.format.Data = &signal.base[0]
+	signal.format.FillBlack()
+	signal.format.Data = nil
+	c, err := newCanvas(signal.format, signal.base)
+	if err != nil {
+		return err
+	}
+	draw(c)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(names))
+	for _, name := range names {
+		sender, err := gondi.NewSendInstanceWithOptions(gondi.SendOptions{Name: name, Groups: *groups, ClockVideo: true})
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		defer sender.Close()
+
+		fullName := name
+		if source, err := sender.SourceName(); err == nil {
+			fullName = source.Name()
+		}
+		fmt.Printf("Sending %s: %dx%d %s at %s, %s audio\n", fullName, signal.format.Xres, signal.format.Yres,
+			signal.format.FourCC, signal.rate, signal.audio)
+
+		wg.Add(2)
+		go func(name string) {
+			defer wg.Done()
+			reportSender(ctx, name, sender)
+		}(name)
+		go func(name string) {
+			defer wg.Done()
+			if err := signal.send(ctx, sender); err != nil && ctx.Err() == nil {
+				errs <- fmt.Errorf("%s: %w", name, err)
+				cancel()
+			}
+		}(name)
+	}
+
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// Print the tally and connection changes of a sender.
+func reportSender(ctx context.Context, name string, sender *gondi.SendInstance) {
+	tally := sender.WatchTally(ctx)
+	connections := sender.WatchConnections(ctx)
+
+	for tally != nil || connections != nil {
+		select {
+		case change, ok := <-tally:
+			if !ok {
+				tally = nil
+				continue
+			}
+			fmt.Printf("%s %s: program %s, preview %s\n", change.Time.Format("15:04:05"), name, onOff(change.Program), onOff(change.Preview))
+		case change, ok := <-connections:
+			if !ok {
+				connections = nil
+				continue
+			}
+			fmt.Printf("%s %s: %d connections\n", change.Time.Format("15:04:05"), name, change.Connections)
+		}
+	}
+}
+
+func onOff(v bool) string {
+	if v {
+		return "on"
+	}
+	return "off"
+}
+
+// Send the test signal until the context is done. Video is clocked by the sender, and each video frame is
+// followed by the audio for the same frame, so the sync beep and flash line up.
+func (s *testSignal) send(ctx context.Context, sender *gondi.SendInstance) error {
+	pool, err := sender.NewAsyncVideoSender(s.format, 2)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	generator, _ := newAudioGenerator(s.audio, s.sampleRate, s.channels, s.frequency, s.level)
+	cadence := s.rate.AudioCadence(int32(s.sampleRate))
+	maxSamples := int32(0)
+	for _, samples := range cadence {
+		maxSamples = max(maxSamples, samples)
+	}
+	samples := make([]float32, int(maxSamples)*s.channels)
+	audioFrame := gondi.NewAudioFrameV2()
+	audioFrame.SampleRate = int32(s.sampleRate)
+	audioFrame.NumChannels = int32(s.channels)
+	audioFrame.ChannelStride = maxSamples * 4
+	audioFrame.Data = &samples[0]
+
+	fps := s.rate.Float64()
+	for frame := int64(0); ctx.Err() == nil; frame++ {
+		tc := s.start.AddFrames(frame, s.rate.N, s.rate.D)
+
+		// The flash and beep mark the first frame of every second
+		second := frame * int64(s.rate.D) / int64(s.rate.N)
+		flash := s.flash && (frame == 0 || second != (frame-1)*int64(s.rate.D)/int64(s.rate.N))
+
+		video, err := pool.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+		data := pool.Buffer(video)
+		copy(data, s.base)
+		c, _ := newCanvas(video, data)
+
+		if s.moving {
+			drawBox(c, frame, fps)
+		}
+		if flash {
+			c.fill(c.width/4, c.height/4, c.width/2, c.height/2, white)
+		}
+		if s.burnIn {
+			text := tc.FormatSMPTE(s.rate.N, s.rate.D) + " #" + strconv.FormatInt(frame, 10)
+			scale := max(c.height/135, 1)
+			w, h := textSize(text, scale)
+			x, y := (c.width-w)/2, c.height-h-c.height/12
+			c.fill(x-2*scale, y-2*scale, w+3*scale, h+4*scale, black)
+			c.text(x, y, text, scale, white)
+		}
+		if tally := sender.CurrentTally(); tally.Program || tally.Preview {
+			color := green
+			if tally.Program {
+				color = red
+			}
+			c.border(0, 0, c.width, c.height, max(c.height/54, 2), color)
+		}
+
+		video.SetTimecode(tc)
+		if err := pool.Submit(video); err != nil {
+			return err
+		}
+
+		if s.audio == "none" {
+			continue
+		}
+		audioFrame.NumSamples = s.rate.SamplesPerFrame(int32(s.sampleRate), frame)
+		audioFrame.SetTimecode(tc)
+		generator.generate(samples, int(maxSamples), int(audioFrame.NumSamples), flash)
+		if err := sender.SendAudioFrame(audioFrame); err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
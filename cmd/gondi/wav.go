@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Offsets in the header written by createWAV.
+const (
+	wavRIFFSize    = 4
+	wavJunk        = 12
+	wavDS64        = 20
+	wavFactSamples = 82
+	wavDataSize    = 90
+	wavHeaderSize  = 94
+)
+
+// Writes 32 bit float WAV files. The header reserves room for an RF64 ds64 chunk in a JUNK chunk, so the file
+// is turned into RF64 when it grows beyond 4GB, or when RF64 is asked for.
+type wavWriter struct {
+	file     *os.File
+	w        *bufio.Writer
+	channels int
+	rf64     bool
+	samples  int64
+	buf      []byte
+}
+
+func createWAV(path string, sampleRate, channels int, rf64 bool) (*wavWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, wavHeaderSize)
+	copy(header[0:], "RIFF")
+	copy(header[8:], "WAVE")
+	copy(header[wavJunk:], "JUNK")
+	binary.LittleEndian.PutUint32(header[wavJunk+4:], 28)
+
+	fmtChunk := header[48:]
+	copy(fmtChunk, "fmt ")
+	binary.LittleEndian.PutUint32(fmtChunk[4:], 18)
+	binary.LittleEndian.PutUint16(fmtChunk[8:], 3) // WAVE_FORMAT_IEEE_FLOAT
+	binary.LittleEndian.PutUint16(fmtChunk[10:], uint16(channels))
+	binary.LittleEndian.PutUint32(fmtChunk[12:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(fmtChunk[16:], uint32(sampleRate*channels*4))
+	binary.LittleEndian.PutUint16(fmtChunk[20:], uint16(channels*4))
+	binary.LittleEndian.PutUint16(fmtChunk[22:], 32)
+
+	copy(header[wavFactSamples-8:], "fact")
+	binary.LittleEndian.PutUint32(header[wavFactSamples-4:], 4)
+	copy(header[wavDataSize-4:], "data")
+
+	if _, err := file.Write(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &wavWriter{file: file, w: bufio.NewWriterSize(file, 1<<20), channels: channels, rf64: rf64}, nil
+}
+
+// Write interleaved samples.
+func (w *wavWriter) write(samples []float32) error {
+	if cap(w.buf) < len(samples)*4 {
+		w.buf = make([]byte, len(samples)*4)
+	}
+	buf := w.buf[:len(samples)*4]
+	for i, v := range samples {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+
+	w.samples += int64(len(samples) / w.channels)
+	_, err := w.w.Write(buf)
+	return err
+}
+
+// Fill in the sizes in the header and close the file.
+func (w *wavWriter) close() error {
+	if err := w.w.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+
+	dataSize := w.samples * int64(w.channels) * 4
+	riffSize := dataSize + wavHeaderSize - 8
+	header := make([]byte, wavHeaderSize)
+	if _, err := w.file.ReadAt(header, 0); err != nil {
+		w.file.Close()
+		return err
+	}
+
+	if w.rf64 || riffSize > math.MaxUint32 {
+		copy(header[0:], "RF64")
+		binary.LittleEndian.PutUint32(header[wavRIFFSize:], math.MaxUint32)
+		copy(header[wavJunk:], "ds64")
+		binary.LittleEndian.PutUint64(header[wavDS64:], uint64(riffSize))
+		binary.LittleEndian.PutUint64(header[wavDS64+8:], uint64(dataSize))
+		binary.LittleEndian.PutUint64(header[wavDS64+16:], uint64(w.samples))
+		binary.LittleEndian.PutUint32(header[wavFactSamples:], math.MaxUint32)
+		binary.LittleEndian.PutUint32(header[wavDataSize:], math.MaxUint32)
+	} else {
+		binary.LittleEndian.PutUint32(header[wavRIFFSize:], uint32(riffSize))
+		binary.LittleEndian.PutUint32(header[wavFactSamples:], uint32(w.samples))
+		binary.LittleEndian.PutUint32(header[wavDataSize:], uint32(dataSize))
+	}
+
+	if _, err := w.file.WriteAt(header, 0); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// Reads 16 or 24 bit integer, or 32 bit float, WAV and RF64 files as float samples.
+type wavReader struct {
+	file       *os.File
+	r          *bufio.Reader
+	sampleRate int
+	channels   int
+	format     uint16
+	bits       int
+	dataStart  int64
+	dataSize   int64
+	position   int64
+	buf        []byte
+}
+
+func openWAV(path string) (*wavReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := readWAVHeader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	w.file = file
+	if err := w.rewind(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func readWAVHeader(file *os.File) (*wavReader, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return nil, err
+	}
+	rf64 := string(header[0:4]) == "RF64"
+	if (!rf64 && string(header[0:4]) != "RIFF") || string(header[8:12]) != "WAVE" {
+		return nil, errors.New("not a WAV file")
+	}
+
+	w := &wavReader{}
+	var ds64DataSize int64
+	offset := int64(12)
+	for {
+		chunk := make([]byte, 8)
+		if _, err := file.ReadAt(chunk, offset); err != nil {
+			return nil, errors.New("no audio data found")
+		}
+		id, size := string(chunk[0:4]), int64(binary.LittleEndian.Uint32(chunk[4:]))
+		offset += 8
+
+		switch id {
+		case "ds64":
+			ds64 := make([]byte, 16)
+			if _, err := file.ReadAt(ds64, offset); err != nil {
+				return nil, err
+			}
+			ds64DataSize = int64(binary.LittleEndian.Uint64(ds64[8:]))
+		case "fmt ":
+			fmtChunk := make([]byte, 16)
+			if _, err := file.ReadAt(fmtChunk, offset); err != nil {
+				return nil, err
+			}
+			w.format = binary.LittleEndian.Uint16(fmtChunk[0:])
+			w.channels = int(binary.LittleEndian.Uint16(fmtChunk[2:]))
+			w.sampleRate = int(binary.LittleEndian.Uint32(fmtChunk[4:]))
+			w.bits = int(binary.LittleEndian.Uint16(fmtChunk[14:]))
+			if w.format == 0xfffe && size >= 40 {
+				// WAVE_FORMAT_EXTENSIBLE, the format is the first two bytes of the sub format GUID
+				sub := make([]byte, 2)
+				if _, err := file.ReadAt(sub, offset+24); err != nil {
+					return nil, err
+				}
+				w.format = binary.LittleEndian.Uint16(sub)
+			}
+		case "data":
+			if w.channels == 0 {
+				return nil, errors.New("data chunk before fmt chunk")
+			}
+			if !(w.format == 1 && (w.bits == 16 || w.bits == 24)) && !(w.format == 3 && w.bits == 32) {
+				return nil, fmt.Errorf("unsupported sample format %d with %d bits", w.format, w.bits)
+			}
+			w.dataStart = offset
+			w.dataSize = size
+			if rf64 && size == math.MaxUint32 {
+				w.dataSize = ds64DataSize
+			}
+			return w, nil
+		}
+
+		offset += size + size%2
+	}
+}
+
+// Get the number of bytes of one sample on all channels.
+func (w *wavReader) blockSize() int {
+	return w.channels * w.bits / 8
+}
+
+// Read up to samples samples per channel into planar data, with channels stride samples apart. The rest is
+// filled with silence. Returns the number of samples read, which is 0 at the end of the file.
+func (w *wavReader) read(data []float32, stride, samples int) (int, error) {
+	block := w.blockSize()
+	available := int((w.dataSize - w.position) / int64(block))
+	n := min(samples, available)
+
+	buf := w.buffer(n * block)
+	if _, err := io.ReadFull(w.r, buf); err != nil {
+		return 0, err
+	}
+	w.position += int64(len(buf))
+
+	bytes := w.bits / 8
+	for i := 0; i < n; i++ {
+		for ch := 0; ch < w.channels; ch++ {
+			b := buf[(i*w.channels+ch)*bytes:]
+			var v float32
+			switch {
+			case w.format == 3:
+				v = math.Float32frombits(binary.LittleEndian.Uint32(b))
+			case w.bits == 16:
+				v = float32(int16(binary.LittleEndian.Uint16(b))) / 32768
+			case w.bits == 24:
+				v = float32(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / 8388608
+			}
+			data[ch*stride+i] = v
+		}
+	}
+	for ch := 0; ch < w.channels; ch++ {
+		for i := n; i < samples; i++ {
+			data[ch*stride+i] = 0
+		}
+	}
+
+	return n, nil
+}
+
+// Go back to the first sample.
+func (w *wavReader) rewind() error {
+	if _, err := w.file.Seek(w.dataStart, io.SeekStart); err != nil {
+		return err
+	}
+	if w.r == nil {
+		w.r = bufio.NewReaderSize(w.file, 1<<20)
+	} else {
+		w.r.Reset(w.file)
+	}
+	w.position = 0
+	return nil
+}
+
+func (w *wavReader) buffer(size int) []byte {
+	if cap(w.buf) < size {
+		w.buf = make([]byte, size)
+	}
+	return w.buf[:size]
+}
+
+func (w *wavReader) close() error {
+	return w.file.Close()
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/bitfocus/gondi"
+)
+
+// Writes video frames to a file, either as YUV4MPEG2 or as raw frames in their FourCC. Raw frames are written
+// without line padding, and UYVA frames include their alpha plane.
+type videoWriter struct {
+	file   *os.File
+	w      *bufio.Writer
+	y4m    bool
+	format *gondi.VideoFrameV2
+	buf    []byte
+}
+
+func createVideo(path string, y4m bool) (*videoWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &videoWriter{file: file, w: bufio.NewWriterSize(file, 4<<20), y4m: y4m}, nil
+}
+
+// Get the Y4M header for the format of a frame. UYVY and UYVA are written as 4:2:2, without the alpha plane,
+// and BGRA and BGRX are converted to 4:4:4.
+func y4mHeader(frame *gondi.VideoFrameV2) (string, error) {
+	var colorspace string
+	switch frame.FourCC {
+	case gondi.FourCCTypeUYVY, gondi.FourCCTypeUYVA:
+		colorspace = "422"
+	case gondi.FourCCTypeBGRA, gondi.FourCCTypeBGRX:
+		colorspace = "444"
+	default:
+		return "", fmt.Errorf("unsupported FourCC %s", frame.FourCC)
+	}
+
+	interlacing := "p"
+	if frame.FrameFormatType == gondi.FrameFormatInterleaved {
+		interlacing = "t"
+	}
+
+	// The pixel aspect ratio follows from the picture aspect ratio and the resolution
+	aspect := "1:1"
+	if frame.PictureAspectRatio > 0 {
+		par := float64(frame.PictureAspectRatio) * float64(frame.Yres) / float64(frame.Xres)
+		aspect = fmt.Sprintf("%d:1000", int(math.Round(par*1000)))
+	}
+
+	return fmt.Sprintf("YUV4MPEG2 W%d H%d F%d:%d I%s A%s C%s\n",
+		frame.Xres, frame.Yres, frame.FrameRateN, frame.FrameRateD, interlacing, aspect, colorspace), nil
+}
+
+// Check that a frame has the same format as the first one.
+func sameVideoFormat(a, b *gondi.VideoFrameV2) bool {
+	return a.Xres == b.Xres && a.Yres == b.Yres && a.FourCC == b.FourCC &&
+		a.FrameRateN == b.FrameRateN && a.FrameRateD == b.FrameRateD && a.FrameFormatType == b.FrameFormatType
+}
+
+func (w *videoWriter) write(frame *gondi.VideoFrameV2) error {
+	if w.format == nil {
+		format := *frame
+		format.Data, format.Metadata = nil, nil
+		w.format = &format
+
+		if w.y4m {
+			header, err := y4mHeader(frame)
+			if err != nil {
+				return err
+			}
+			if _, err := w.w.WriteString(header); err != nil {
+				return err
+			}
+		}
+	} else if !sameVideoFormat(w.format, frame) {
+		return fmt.Errorf("video format changed from %dx%d %s to %dx%d %s during recording",
+			w.format.Xres, w.format.Yres, w.format.FourCC, frame.Xres, frame.Yres, frame.FourCC)
+	}
+
+	if w.y4m {
+		if _, err := w.w.WriteString("FRAME\n"); err != nil {
+			return err
+		}
+		_, err := w.w.Write(w.planar(frame))
+		return err
+	}
+
+	_, err := w.w.Write(w.packed(frame))
+	return err
+}
+
+// Get the frame data without line padding.
+func (w *videoWriter) packed(frame *gondi.VideoFrameV2) []byte {
+	data := frame.GetData()
+	height := int(frame.Yres)
+	stride := int(frame.LineStride)
+	if stride == 0 {
+		stride = int(frame.FourCC.LineStride(frame.Xres))
+	}
+	row := int(frame.FourCC.LineStride(frame.Xres))
+	if row == stride {
+		return data
+	}
+
+	buf := w.buffer(row*height + len(data) - stride*height)
+	for y := 0; y < height; y++ {
+		copy(buf[y*row:(y+1)*row], data[y*stride:])
+	}
+	copy(buf[row*height:], data[stride*height:])
+	return buf
+}
+
+// Convert the frame to planar Y, Cb and Cr.
+func (w *videoWriter) planar(frame *gondi.VideoFrameV2) []byte {
+	data := frame.GetData()
+	width, height := int(frame.Xres), int(frame.Yres)
+	stride := int(frame.LineStride)
+	if stride == 0 {
+		stride = int(frame.FourCC.LineStride(frame.Xres))
+	}
+
+	if frame.FourCC == gondi.FourCCTypeUYVY || frame.FourCC == gondi.FourCCTypeUYVA {
+		chroma := width / 2
+		buf := w.buffer(width*height + 2*chroma*height)
+		luma, cb, cr := buf[:width*height], buf[width*height:width*height+chroma*height], buf[width*height+chroma*height:]
+		for y := 0; y < height; y++ {
+			line := data[y*stride:]
+			for x := 0; x < chroma; x++ {
+				cb[y*chroma+x] = line[x*4]
+				luma[y*width+2*x] = line[x*4+1]
+				cr[y*chroma+x] = line[x*4+2]
+				luma[y*width+2*x+1] = line[x*4+3]
+			}
+		}
+		return buf
+	}
+
+	buf := w.buffer(3 * width * height)
+	luma, cb, cr := buf[:width*height], buf[width*height:2*width*height], buf[2*width*height:]
+	for y := 0; y < height; y++ {
+		line := data[y*stride:]
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			luma[i], cb[i], cr[i] = rgb{line[x*4+2], line[x*4+1], line[x*4]}.ycbcr()
+		}
+	}
+	return buf
+}
+
+func (w *videoWriter) buffer(size int) []byte {
+	if cap(w.buf) < size {
+		w.buf = make([]byte, size)
+	}
+	return w.buf[:size]
+}
+
+func (w *videoWriter) close() error {
+	if err := w.w.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// Reads YUV4MPEG2 or raw video files, and converts the frames for sending. Y4M files with 4:2:0, 4:2:2 or
+// 4:4:4 chroma are sent as UYVY, raw files are sent in their own FourCC.
+type videoReader struct {
+	file      *os.File
+	r         *bufio.Reader
+	y4m       bool
+	format    *gondi.VideoFrameV2
+	chroma    string
+	start     int64
+	frameSize int
+	buf       []byte
+}
+
+// Open a Y4M file, the format is taken from its header.
+func openY4M(path string) (*videoReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReaderSize(file, 4<<20)
+	line, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "YUV4MPEG2 ") {
+		file.Close()
+		return nil, fmt.Errorf("%s is not a YUV4MPEG2 file", path)
+	}
+
+	format := gondi.NewVideoFrameV2()
+	format.FourCC = gondi.FourCCTypeUYVY
+	format.SetFrameRate(gondi.FrameRate25)
+	chroma := "420"
+	for _, param := range strings.Fields(line)[1:] {
+		value := param[1:]
+		switch param[0] {
+		case 'W':
+			fmt.Sscan(value, &format.Xres)
+		case 'H':
+			fmt.Sscan(value, &format.Yres)
+		case 'F':
+			var n, d int32
+			if _, err := fmt.Sscanf(value, "%d:%d", &n, &d); err == nil {
+				format.SetFrameRate(gondi.FrameRate{N: n, D: d})
+			}
+		case 'I':
+			if value == "t" {
+				format.FrameFormatType = gondi.FrameFormatInterleaved
+			}
+		case 'A':
+			var n, d int32
+			if _, err := fmt.Sscanf(value, "%d:%d", &n, &d); err == nil && n > 0 && d > 0 && format.Yres > 0 {
+				format.PictureAspectRatio = float32(n) / float32(d) * float32(format.Xres) / float32(format.Yres)
+			}
+		case 'C':
+			chroma = value
+		}
+	}
+
+	v := &videoReader{file: file, r: r, y4m: true, format: format, start: int64(len(line))}
+	width, height := int(format.Xres), int(format.Yres)
+	switch {
+	case strings.HasPrefix(chroma, "420"):
+		v.chroma = "420"
+		v.frameSize = width*height + 2*((width+1)/2)*((height+1)/2)
+	case chroma == "422":
+		v.chroma = "422"
+		v.frameSize = width*height + 2*((width+1)/2)*height
+	case chroma == "444":
+		v.chroma = "444"
+		v.frameSize = 3 * width * height
+	default:
+		file.Close()
+		return nil, fmt.Errorf("unsupported Y4M colorspace C%s", chroma)
+	}
+	if width <= 0 || height <= 0 || width%2 != 0 {
+		file.Close()
+		return nil, fmt.Errorf("unsupported Y4M resolution %dx%d", width, height)
+	}
+	format.LineStride = format.FourCC.LineStride(format.Xres)
+
+	return v, nil
+}
+
+// Open a raw video file in the given format, as written by record.
+func openRaw(path string, format *gondi.VideoFrameV2) (*videoReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := *format
+	f.LineStride = f.FourCC.LineStride(f.Xres)
+	if f.LineStride == 0 {
+		file.Close()
+		return nil, fmt.Errorf("unsupported FourCC %s", f.FourCC)
+	}
+
+	return &videoReader{file: file, r: bufio.NewReaderSize(file, 4<<20), format: &f, frameSize: f.DataSize()}, nil
+}
+
+// Read the next frame into data, which has the size of the format. Returns io.EOF at the end of the file.
+func (v *videoReader) read(data []byte) error {
+	if !v.y4m {
+		_, err := io.ReadFull(v.r, data)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return err
+	}
+
+	line, err := v.r.ReadString('\n')
+	if err != nil {
+		return io.EOF
+	}
+	if !strings.HasPrefix(line, "FRAME") {
+		return errors.New("invalid Y4M frame header")
+	}
+
+	buf := v.buffer(v.frameSize)
+	if _, err := io.ReadFull(v.r, buf); err != nil {
+		return io.EOF
+	}
+
+	width, height := int(v.format.Xres), int(v.format.Yres)
+	luma := buf[:width*height]
+	stride := int(v.format.LineStride)
+
+	switch v.chroma {
+	case "420", "422":
+		cw := (width + 1) / 2
+		ch := height
+		if v.chroma == "420" {
+			ch = (height + 1) / 2
+		}
+		cb, cr := buf[width*height:width*height+cw*ch], buf[width*height+cw*ch:]
+		for y := 0; y < height; y++ {
+			cy := y
+			if v.chroma == "420" {
+				cy = y / 2
+			}
+			line := data[y*stride:]
+			for x := 0; x < width/2; x++ {
+				line[x*4] = cb[cy*cw+x]
+				line[x*4+1] = luma[y*width+2*x]
+				line[x*4+2] = cr[cy*cw+x]
+				line[x*4+3] = luma[y*width+2*x+1]
+			}
+		}
+	case "444":
+		cb, cr := buf[width*height:2*width*height], buf[2*width*height:]
+		for y := 0; y < height; y++ {
+			line := data[y*stride:]
+			for x := 0; x < width/2; x++ {
+				i := y*width + 2*x
+				line[x*4] = uint8((int(cb[i]) + int(cb[i+1]) + 1) / 2)
+				line[x*4+1] = luma[i]
+				line[x*4+2] = uint8((int(cr[i]) + int(cr[i+1]) + 1) / 2)
+				line[x*4+3] = luma[i+1]
+			}
+		}
+	}
+
+	return nil
+}
+
+// Go back to the first frame.
+func (v *videoReader) rewind() error {
+	if _, err := v.file.Seek(v.start, io.SeekStart); err != nil {
+		return err
+	}
+	v.r.Reset(v.file)
+	return nil
+}
+
+func (v *videoReader) buffer(size int) []byte {
+	if cap(v.buf) < size {
+		v.buf = make([]byte, size)
+	}
+	return v.buf[:size]
+}
+
+func (v *videoReader) close() error {
+	return v.file.Close()
+}
@@ -0,0 +1,172 @@
+package gondi
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/bitfocus/gondi/metadata"
+)
+
+// ConnectionMetadata manages the connection metadata of an instance as a set of keyed entries, such as
+// product information and capabilities. Every change re-pushes the complete set to the SDK, in the order
+// the keys were first added.
+//
+// The frames handed to the SDK are owned by the manager, which is kept by the instance, so they stay alive for
+// as long as they are part of the set. Do not use AddConnectionMetadata or ClearConnectionMetadata directly
+// on an instance that is managed this way, as the next change will replace them.
+type ConnectionMetadata struct {
+	mu      sync.Mutex
+	replace func(frames []*MetadataFrame) error
+	keys    []string
+	entries map[string]*MetadataFrame
+}
+
+// Get the connection metadata manager of this sender.
+func (p *SendInstance) ConnectionMetadata() *ConnectionMetadata {
+	p.connectionMetadataOnce.Do(func() {
+		p.connectionMetadata = newConnectionMetadata(p.replaceConnectionMetadata)
+	})
+	return p.connectionMetadata
+}
+
+// Get the connection metadata manager of this receiver.
+func (p *RecvInstance) ConnectionMetadata() *ConnectionMetadata {
+	p.connectionMetadataOnce.Do(func() {
+		p.connectionMetadata = newConnectionMetadata(p.replaceConnectionMetadata)
+	})
+	return p.connectionMetadata
+}
+
+// Create a manager replacing the connection metadata of an instance with the given method.
+func newConnectionMetadata(replace func(frames []*MetadataFrame) error) *ConnectionMetadata {
+	return &ConnectionMetadata{
+		replace: replace,
+		entries: make(map[string]*MetadataFrame),
+	}
+}
+
+// Set an entry, encoded with metadata.Encode, and push the updated set.
+func (c *ConnectionMetadata) Set(key string, v any) error {
+	return c.Update(map[string]any{key: v})
+}
+
+// Set several entries at once, and push the updated set a single time. New keys are added in sorted order.
+// If the push fails, the entries are left as they were.
+func (c *ConnectionMetadata) Update(entries map[string]any) error {
+	frames := make(map[string]*MetadataFrame, len(entries))
+	for key, v := range entries {
+		data, err := metadata.Encode(v)
+		if err != nil {
+			return err
+		}
+		frames[key] = NewMetadataFrame(data)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys, updated := c.copyEntries()
+	for _, key := range sortedKeys(frames) {
+		if _, ok := updated[key]; !ok {
+			keys = append(keys, key)
+		}
+		updated[key] = frames[key]
+	}
+	return c.commit(keys, updated)
+}
+
+// Set the ndi_product entry.
+func (c *ConnectionMetadata) SetProduct(product metadata.Product) error {
+	return c.Set("ndi_product", product)
+}
+
+// Set the ndi_capabilities entry.
+func (c *ConnectionMetadata) SetCapabilities(capabilities metadata.Capabilities) error {
+	return c.Set("ndi_capabilities", capabilities)
+}
+
+// Remove an entry, and push the updated set. If the push fails, the entry is kept.
+func (c *ConnectionMetadata) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok {
+		return nil
+	}
+	keys, updated := c.copyEntries()
+	delete(updated, key)
+	for i, k := range keys {
+		if k == key {
+			keys = append(keys[:i], keys[i+1:]...)
+			break
+		}
+	}
+	return c.commit(keys, updated)
+}
+
+// Get the XML of an entry.
+func (c *ConnectionMetadata) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	frame, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	return frame.GetData(), true
+}
+
+// Get the keys of all entries, in the order they are sent.
+func (c *ConnectionMetadata) Keys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.keys...)
+}
+
+// Push the complete set again.
+func (c *ConnectionMetadata) Apply() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.push(c.keys, c.entries)
+}
+
+// Copy the current set, so a change can be made to the copy and only kept once it has been pushed. Called with mu held.
+func (c *ConnectionMetadata) copyEntries() ([]string, map[string]*MetadataFrame) {
+	entries := make(map[string]*MetadataFrame, len(c.entries))
+	for key, frame := range c.entries {
+		entries[key] = frame
+	}
+	return append([]string(nil), c.keys...), entries
+}
+
+// Push a changed set, and make it the current one if that succeeded. Called with mu held.
+func (c *ConnectionMetadata) commit(keys []string, entries map[string]*MetadataFrame) error {
+	if err := c.push(keys, entries); err != nil {
+		return err
+	}
+	c.keys, c.entries = keys, entries
+	return nil
+}
+
+// Replace the connection metadata of the instance with the given set. Called with mu held.
+// The SDK can only clear the list and add to it, so the update is not atomic for receivers: one connecting while the
+// set is being replaced may get only part of it, or none. Metadata sends on the instance wait for the update, so
+// they are never sent between the clear and the adds.
+func (c *ConnectionMetadata) push(keys []string, entries map[string]*MetadataFrame) error {
+	frames := make([]*MetadataFrame, len(keys))
+	for i, key := range keys {
+		frames[i] = entries[key]
+	}
+	return c.replace(frames)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
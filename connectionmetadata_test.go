@@ -0,0 +1,70 @@
+package gondi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitfocus/gondi/metadata"
+)
+
+func TestConnectionMetadata(t *testing.T) {
+	// The SDK keeps the connection metadata as a list that can only be cleared and added to
+	var current []string
+	pushes := 0
+	stubLibrary(t, &ndilib_send_clear_connection_metadata, func(instance uintptr) {
+		current = nil
+		pushes++
+	})
+	stubLibrary(t, &ndilib_send_add_connection_metadata, func(instance uintptr, frame uintptr) {
+		current = append(current, stubArg[MetadataFrame](frame).GetData())
+	})
+
+	sender := &SendInstance{}
+	c := sender.ConnectionMetadata()
+
+	if err := c.SetProduct(metadata.Product{LongName: "Test", Manufacturer: "bitfocus"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetCapabilities(metadata.Capabilities{WebControl: "http://%IP%/"}); err != nil {
+		t.Fatal(err)
+	}
+	c.Set("custom", `<custom value="1"/>`)
+	c.SetProduct(metadata.Product{LongName: "Test 2"})
+
+	want := []string{
+		`<ndi_product long_name="Test 2"></ndi_product>`,
+		`<ndi_capabilities web_control="http://%IP%/"></ndi_capabilities>`,
+		`<custom value="1"/>`,
+	}
+	if !reflect.DeepEqual(current, want) {
+		t.Errorf("connection metadata is %q, want %q", current, want)
+	}
+
+	c.Delete("ndi_capabilities")
+	if !reflect.DeepEqual(c.Keys(), []string{"ndi_product", "custom"}) {
+		t.Errorf("keys are %q after delete", c.Keys())
+	}
+	if len(current) != 2 || pushes != 5 {
+		t.Errorf("got %d entries after %d pushes, want 2 entries after 5 pushes", len(current), pushes)
+	}
+
+	c.Update(map[string]any{"b": "<b/>", "a": "<a/>"})
+	if !reflect.DeepEqual(c.Keys(), []string{"ndi_product", "custom", "a", "b"}) || pushes != 6 {
+		t.Errorf("keys are %q after %d pushes", c.Keys(), pushes)
+	}
+	if data, ok := c.Get("custom"); !ok || data != `<custom value="1"/>` {
+		t.Errorf("Get returned %q, %v", data, ok)
+	}
+
+	// A failed push leaves the entries as they were
+	sender.state.close(func() {})
+	if err := c.Update(map[string]any{"custom": `<custom value="2"/>`, "c": "<c/>"}); err != ErrClosed {
+		t.Errorf("Update returned %v, want the error of the push", err)
+	}
+	if err := c.Delete("a"); err != ErrClosed {
+		t.Errorf("Delete returned %v, want the error of the push", err)
+	}
+	if data, _ := c.Get("custom"); data != `<custom value="1"/>` || !reflect.DeepEqual(c.Keys(), []string{"ndi_product", "custom", "a", "b"}) {
+		t.Errorf("entries changed by failed pushes, custom is %q and keys are %q", data, c.Keys())
+	}
+}
@@ -0,0 +1,195 @@
+package gondi
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// How often the number of connections is polled while there are connections. While there are none, the SDK
+// is asked to wait for a connection for the same amount of time, so new connections are seen immediately.
+const connectionPollInterval = 250 * time.Millisecond
+
+// A number of connections reported by WatchConnections, with the time the change was seen.
+type ConnectionChange struct {
+	Connections int32
+	Time        time.Time
+}
+
+// What RenderWhenConnected sends while no receivers are connected.
+type IdleMode int
+
+const (
+	// Repeat the last rendered frame, or black if nothing has been rendered yet.
+	IdleRepeatLast IdleMode = iota
+	// Send black frames.
+	IdleBlack
+	// Do not send anything.
+	IdleNothing
+)
+
+// Options for RenderWhenConnected.
+type RenderOptions struct {
+	// The frame to render into and send. Its format, frame rate and data buffer must be set up by the caller.
+	Frame *VideoFrameV2
+
+	// What to send while no receivers are connected. Default is IdleRepeatLast.
+	Idle IdleMode
+
+	// How often to send a frame while no receivers are connected. Default is once per second.
+	IdleInterval time.Duration
+}
+
+// Watch the number of receivers connected to this sender. The returned channel first receives the current number
+// of connections, and then every change to it. The channel is closed when the context is done or the instance is destroyed.
+// A watcher that does not keep up misses intermediate numbers, but the last one it receives is always the current one.
+func (p *SendInstance) WatchConnections(ctx context.Context) <-chan ConnectionChange {
+	assertLibrary()
+
+	p.workers.start("connections", p.pollConnections)
+
+	p.connectionsMu.Lock()
+	defer p.connectionsMu.Unlock()
+
+	if p.connections.Time.IsZero() {
+		p.setConnections(p.GetNumberOfConnections(0))
+	}
+
+	return p.connectionWatchers.add(ctx, 16, &p.connections)
+}
+
+// Store a polled number of connections, and return true if it changed. Called with connectionsMu held.
+func (p *SendInstance) setConnections(connections int32) bool {
+	if !p.connections.Time.IsZero() && p.connections.Connections == connections {
+		return false
+	}
+	p.connections = ConnectionChange{connections, time.Now()}
+
+	return true
+}
+
+func (p *SendInstance) pollConnections(stop <-chan struct{}) {
+	var connections int32
+
+	for {
+		if connections > 0 {
+			select {
+			case <-stop:
+				return
+			case <-time.After(connectionPollInterval):
+			}
+			connections = p.GetNumberOfConnections(0)
+		} else {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			connections = p.GetNumberOfConnections(uint32(connectionPollInterval.Milliseconds()))
+		}
+
+		p.connectionsMu.Lock()
+		if p.setConnections(connections) {
+			p.connectionWatchers.publish(p.connections)
+		}
+		p.connectionsMu.Unlock()
+	}
+}
+
+// Run a render loop that only calls render while at least one receiver is connected. The render function fills in
+// options.Frame, which is then sent. While nobody is connected, the last frame or black is sent at a low rate instead,
+// so receivers that connect see a picture immediately.
+//
+// If the sender was created with clockVideo, the SDK paces the loop, otherwise it is paced by the frame rate of the frame.
+// The loop runs until the context is done, render returns an error, or the instance is closed, and returns the reason.
+func (p *SendInstance) RenderWhenConnected(ctx context.Context, options RenderOptions, render func(frame *VideoFrameV2) error) error {
+	frame := options.Frame
+	if frame == nil {
+		return errors.New("render options have no frame")
+	}
+	if render == nil {
+		return errors.New("render function is nil")
+	}
+	if options.IdleInterval <= 0 {
+		options.IdleInterval = time.Second
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	changes := p.WatchConnections(ctx)
+
+	var connections int32
+	rendered := false
+	frameDuration := frame.GetFrameRate().FrameDuration()
+	next := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case change, ok := <-changes:
+			if !ok {
+				return closedReason(ctx)
+			}
+			connections = change.Connections
+			continue
+		default:
+		}
+
+		if connections == 0 {
+			if options.Idle != IdleNothing {
+				if !rendered || options.Idle == IdleBlack {
+					frame.FillBlack()
+					rendered = false
+				}
+				if err := p.SendVideoFrame(frame); err != nil {
+					return err
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case change, ok := <-changes:
+				if !ok {
+					return closedReason(ctx)
+				}
+				connections = change.Connections
+				next = time.Now()
+			case <-time.After(options.IdleInterval):
+			}
+			continue
+		}
+
+		if err := render(frame); err != nil {
+			return err
+		}
+		rendered = true
+		if err := p.SendVideoFrame(frame); err != nil {
+			return err
+		}
+
+		if !p.createSettings.clockVideo && frameDuration > 0 {
+			next = next.Add(frameDuration)
+			wait := time.Until(next)
+			if wait < -frameDuration {
+				// Too far behind, do not try to catch up
+				next = time.Now()
+			} else if wait > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+		}
+	}
+}
+
+// Get the reason a watcher channel was closed, either the context or the instance.
+func closedReason(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ErrClosed
+}
@@ -0,0 +1,19 @@
+package gondi
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRenderWhenConnectedOptions(t *testing.T) {
+	// Invalid options are rejected before the library is used
+	p := &SendInstance{}
+	render := func(frame *VideoFrameV2) error { return nil }
+
+	if err := p.RenderWhenConnected(context.Background(), RenderOptions{}, render); err == nil {
+		t.Error("options without a frame were accepted")
+	}
+	if err := p.RenderWhenConnected(context.Background(), RenderOptions{Frame: NewVideoFrameV2()}, nil); err == nil {
+		t.Error("a nil render function was accepted")
+	}
+}
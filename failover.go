@@ -0,0 +1,169 @@
+package gondi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// How long a single discovery poll of a FailoverGroup waits for the sources to change.
+const failoverPollTimeoutMs = 500
+
+// The backup armed by a FailoverGroup, with the time it was armed. Source is nil and Priority is -1 when none
+// of the backups are online.
+type FailoverChange struct {
+	Source   *Source
+	Priority int
+	Time     time.Time
+}
+
+// FailoverGroup keeps the failover source of a sender pointed at the highest priority backup that is online.
+// Backups are NDI source names in priority order, and a finder is used to see which of them are online.
+// Whenever that changes, SetFailover is called on the sender, so receivers fail over deterministically.
+type FailoverGroup struct {
+	sender *SendInstance
+	finder *FindInstance
+
+	mu       sync.Mutex
+	backups  []string
+	sources  []*Source
+	armed    FailoverChange
+	watchers subscribers[FailoverChange]
+
+	workers workers
+}
+
+// Create a failover group for this sender, with backup source names in priority order, for instance
+// "MACHINE (Playout B)". The finder is used for discovery, and must outlive the group, it is not closed by it.
+// The group runs until Close is called, or the sender or finder is closed.
+func (p *SendInstance) NewFailoverGroup(finder *FindInstance, backups ...string) (*FailoverGroup, error) {
+	if p.state.isClosed() {
+		return nil, ErrClosed
+	}
+	if finder == nil {
+		return nil, errors.New("a failover group needs a finder")
+	}
+
+	g := &FailoverGroup{
+		sender:  p,
+		finder:  finder,
+		backups: append([]string(nil), backups...),
+		armed:   FailoverChange{Priority: -1},
+	}
+	g.workers.start("discovery", g.discover)
+
+	return g, nil
+}
+
+// Replace the backup source names, in priority order. The failover source is updated right away from the last
+// discovered sources.
+func (g *FailoverGroup) SetBackups(backups ...string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.backups = append([]string(nil), backups...)
+
+	return g.update()
+}
+
+// Get the backup source names, in priority order.
+func (g *FailoverGroup) Backups() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return append([]string(nil), g.backups...)
+}
+
+// Get the currently armed backup, see FailoverChange.
+func (g *FailoverGroup) Armed() FailoverChange {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.armed
+}
+
+// Watch which backup is armed. The returned channel first receives the currently armed backup, and then every change.
+// A watcher that does not keep up misses intermediate changes, but the last one it receives is always the armed backup.
+// The channel is closed when the context is done or the group stops.
+func (g *FailoverGroup) Watch(ctx context.Context) <-chan FailoverChange {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.watchers.add(ctx, 16, &g.armed)
+}
+
+// Stop managing the failover source. The failover source that was last set is left in place.
+func (g *FailoverGroup) Close() error {
+	g.workers.shutdown()
+	g.watchers.close()
+
+	return nil
+}
+
+func (g *FailoverGroup) discover(stop <-chan struct{}) {
+	defer g.watchers.close()
+
+	first := true
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if g.sender.state.isClosed() || g.finder.state.isClosed() {
+			return
+		}
+
+		if !g.finder.WaitForSources(failoverPollTimeoutMs) && !first {
+			continue
+		}
+		first = false
+
+		if err := g.setSources(g.finder.GetCurrentSources()); errors.Is(err, ErrClosed) {
+			return
+		}
+	}
+}
+
+// Store the discovered sources, and update the failover source.
+func (g *FailoverGroup) setSources(sources []*Source) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.sources = sources
+
+	return g.update()
+}
+
+// Arm the highest priority backup that is online, if it is not already armed. Called with mu held.
+func (g *FailoverGroup) update() error {
+	var source *Source
+	priority := -1
+
+	for i, name := range g.backups {
+		for _, s := range g.sources {
+			if s.Name() == name {
+				source, priority = NewSource(s.Name(), s.Address()), i
+				break
+			}
+		}
+		if source != nil {
+			break
+		}
+	}
+
+	if priority == g.armed.Priority && source.Name() == g.armed.Source.Name() && source.Address() == g.armed.Source.Address() {
+		return nil
+	}
+
+	if err := g.sender.SetFailover(source); err != nil {
+		return err
+	}
+
+	g.armed = FailoverChange{Source: source, Priority: priority, Time: time.Now()}
+	g.watchers.publish(g.armed)
+
+	return nil
+}
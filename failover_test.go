@@ -0,0 +1,68 @@
+package gondi
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFailoverGroup(t *testing.T) {
+	// The SDK copies the failover source when it is set
+	calls := 0
+	var failover *Source
+	stubLibrary(t, &ndilib_send_set_failover, func(instance uintptr, source uintptr) {
+		calls++
+		failover = nil
+		if s := stubArg[Source](source); s != nil {
+			failover = NewSource(s.Name(), s.Address())
+		}
+	})
+
+	// Without a finder, so the discovered sources are set by the test
+	g := &FailoverGroup{
+		sender:  &SendInstance{},
+		backups: []string{"B (Playout)", "C (Playout)"},
+		armed:   FailoverChange{Priority: -1},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := g.Watch(ctx)
+
+	if change := <-changes; change.Source != nil || change.Priority != -1 {
+		t.Errorf("initial state has %v armed", change.Source)
+	}
+
+	// Only the second backup is online
+	g.setSources([]*Source{NewSource("A (Other)", "10.0.0.1:5961"), NewSource("C (Playout)", "10.0.0.3:5961")})
+	if change := <-changes; change.Priority != 1 || failover.Name() != "C (Playout)" {
+		t.Errorf("armed priority %d, %q", change.Priority, failover.Name())
+	}
+
+	// The primary backup comes online
+	g.setSources([]*Source{NewSource("B (Playout)", "10.0.0.2:5961"), NewSource("C (Playout)", "10.0.0.3:5961")})
+	if change := <-changes; change.Priority != 0 || failover.Address() != "10.0.0.2:5961" {
+		t.Errorf("armed priority %d, %q", change.Priority, failover.Name())
+	}
+
+	// Rediscovering the same sources does not call SetFailover again
+	before := calls
+	g.setSources([]*Source{NewSource("C (Playout)", "10.0.0.3:5961"), NewSource("B (Playout)", "10.0.0.2:5961")})
+	if calls != before {
+		t.Error("SetFailover was called without a change")
+	}
+
+	// Reordering the backups arms the new primary
+	g.SetBackups("C (Playout)", "B (Playout)")
+	if armed := g.Armed(); armed.Priority != 0 || armed.Source.Name() != "C (Playout)" {
+		t.Errorf("armed %v after reordering", armed.Source)
+	}
+
+	// All backups going offline clears the failover source
+	g.setSources(nil)
+	if g.Armed().Source != nil || failover != nil {
+		t.Error("failover source was not cleared")
+	}
+
+	g.Close()
+	for range changes {
+	}
+}
@@ -2,7 +2,6 @@ package gondi
 
 import (
 	"errors"
-	"fmt"
 	"unsafe"
 )
 
@@ -12,19 +11,14 @@ import (
 // The extraIPs is only used to manually find sources from known ips on different subnets and is comma separated.
 func NewFindInstance(showLocalSources bool, groups string, extraIPs string) (*FindInstance, error) {
 	assertLibrary()
-	settings := &findCreateSettings{showLocalSources, cString(groups), cString(extraIPs)}
 	inst := &FindInstance{}
-
-	if groups == "" {
-		settings.groups = nil
-	}
-	if extraIPs == "" {
-		settings.extraIPs = nil
-	}
+	settings := &findCreateSettings{showLocalSources, inst.strings.cStringOrNil(groups), inst.strings.cStringOrNil(extraIPs)}
+	inst.strings.pin(settings)
 
 	inst.createSettings = settings
 	inst.ndiInstance = ndilib_find_create_v2(uintptr(unsafe.Pointer(settings)))
 	if inst.ndiInstance == 0 {
+		inst.strings.free()
 		return nil, errors.New("unable to create finder instance")
 	}
 
@@ -34,22 +28,30 @@ func NewFindInstance(showLocalSources bool, groups string, extraIPs string) (*Fi
 // Get the current sources from this finder instance. It is recomended to call WaitForSources before this.
 // If you have a UI element to change the source, you should call this function before showing the user the list of sources,
 // to always have the latest list of sources.
+// The returned sources are copies owned by Go, so they stay valid after the next call and after the finder is destroyed.
+// It is safe to call from several goroutines, for instance while RoutingInstance.Follow uses the same finder.
+// After the instance is closed, nil is returned, use IsClosed to tell this apart from no sources.
 func (p *FindInstance) GetCurrentSources() []*Source {
 	assertLibrary()
+	if !p.state.acquire() {
+		return nil
+	}
+	defer p.state.release()
+	p.sourcesMu.Lock()
+	defer p.sourcesMu.Unlock()
 
 	var numSources uint32
 	ret := ndilib_find_get_current_sources(p.ndiInstance, uintptr(unsafe.Pointer(&numSources)))
 
-	fmt.Printf("numSources: %d\n", numSources)
-	sources := make([]*Source, numSources)
-
 	// We take the address and then dereference it to trick go vet from creating a possible misuse of unsafe.Pointer
 	blockp := *(*unsafe.Pointer)(unsafe.Pointer(&ret))
+	if blockp == nil {
+		numSources = 0
+	}
 
-	for i, _ := range sources {
-		sources[i] = (*Source)(blockp)
-		// Increment pointer
-		blockp = unsafe.Add(blockp, unsafe.Sizeof(Source{}))
+	sources := make([]*Source, numSources)
+	for i, source := range unsafe.Slice((*Source)(blockp), numSources) {
+		sources[i] = NewSource(source.Name(), source.Address())
 	}
 
 	return sources
@@ -59,15 +61,35 @@ func (p *FindInstance) GetCurrentSources() []*Source {
 // It will return true if the list of sourcess has changed within the timeout, false otherwise.
 // You are not required to call this function, but it is helpful for getting an initial list of sources,
 // and to detect when the list of sources has changed.
+// After the instance is closed, it returns false immediately, use IsClosed to tell this apart from a timeout.
 func (p *FindInstance) WaitForSources(timeoutMs uint32) bool {
 	assertLibrary()
+	if !p.state.acquire() {
+		return false
+	}
+	defer p.state.release()
 
 	return ndilib_find_wait_for_sources(p.ndiInstance, timeoutMs)
 }
 
-// Destroy this finder instance.
+// Destroy this finder instance. It is safe to call Destroy more than once, and from any goroutine.
 func (p *FindInstance) Destroy() {
+	p.Close()
+}
+
+// Close implements io.Closer, and is the same as Destroy. It always returns nil.
+func (p *FindInstance) Close() error {
 	assertLibrary()
 
-	ndilib_find_destroy(p.ndiInstance)
+	p.state.close(func() {
+		ndilib_find_destroy(p.ndiInstance)
+		p.strings.free()
+	})
+
+	return nil
+}
+
+// Returns true if the instance has been closed or destroyed.
+func (p *FindInstance) IsClosed() bool {
+	return p.state.isClosed()
 }
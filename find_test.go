@@ -0,0 +1,51 @@
+package gondi
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+func TestGetCurrentSourcesConcurrent(t *testing.T) {
+	// Stand in for the SDK, which frees the previous list of sources when it is asked for a new one
+	var previous [][]byte
+	var lists [][]Source
+	stubLibrary(t, &ndilib_find_get_current_sources, func(instance uintptr, numSources uintptr) uintptr {
+		for _, name := range previous {
+			for i := range name {
+				name[i] = 0
+			}
+		}
+		previous = previous[:0]
+
+		list := make([]Source, 3)
+		for i := range list {
+			name := []byte(fmt.Sprintf("MACHINE (Source %d)\x00", i))
+			previous = append(previous, name)
+			list[i].name = &name[0]
+		}
+		lists = append(lists, list)
+
+		*stubArg[uint32](numSources) = uint32(len(list))
+		return uintptr(unsafe.Pointer(&list[0]))
+	})
+
+	finder := &FindInstance{}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				for j, source := range finder.GetCurrentSources() {
+					if want := fmt.Sprintf("MACHINE (Source %d)", j); source.Name() != want {
+						t.Errorf("source %d is %q, want %q", j, source.Name(), want)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
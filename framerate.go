@@ -0,0 +1,197 @@
+package gondi
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A video frame rate as a rational number of frames per second, for instance 30000/1001 for 29.97.
+type FrameRate struct {
+	N, D int32
+}
+
+// Standard broadcast frame rates
+var (
+	FrameRate2398 = FrameRate{24000, 1001}
+	FrameRate24   = FrameRate{24, 1}
+	FrameRate25   = FrameRate{25, 1}
+	FrameRate2997 = FrameRate{30000, 1001}
+	FrameRate30   = FrameRate{30, 1}
+	FrameRate50   = FrameRate{50, 1}
+	FrameRate5994 = FrameRate{60000, 1001}
+	FrameRate60   = FrameRate{60, 1}
+)
+
+// Returns true if both the numerator and denominator are positive.
+func (r FrameRate) IsValid() bool {
+	return r.N > 0 && r.D > 0
+}
+
+// Get the frame rate in frames per second as a floating point number.
+func (r FrameRate) Float64() float64 {
+	if !r.IsValid() {
+		return 0
+	}
+	return float64(r.N) / float64(r.D)
+}
+
+// Get the duration of a single frame, truncated to the nanosecond.
+func (r FrameRate) FrameDuration() time.Duration {
+	if !r.IsValid() {
+		return 0
+	}
+	return time.Duration(int64(r.D) * int64(time.Second) / int64(r.N))
+}
+
+// Returns true if SMPTE timecode at this frame rate uses drop-frame counting.
+func (r FrameRate) IsDropFrame() bool {
+	return isDropFrame(r.N, r.D)
+}
+
+// Get the number of audio samples in a frame at the given sample rate, for the frame number counted from 0.
+// When the sample rate is not a multiple of the frame rate, the number of samples follows a repeating cadence,
+// for instance 1602, 1601, 1602, 1601, 1602 for 48kHz audio at 29.97.
+func (r FrameRate) SamplesPerFrame(sampleRate int32, frame int64) int32 {
+	if !r.IsValid() || sampleRate <= 0 {
+		return 0
+	}
+
+	// Position in the cadence, so the arithmetic does not overflow for large frame numbers
+	length := r.cadenceLength(sampleRate)
+	frame %= length
+	if frame < 0 {
+		frame += length
+	}
+
+	return int32(r.samplesUntil(sampleRate, frame+1) - r.samplesUntil(sampleRate, frame))
+}
+
+// Get the repeating pattern of the number of audio samples per frame at the given sample rate.
+func (r FrameRate) AudioCadence(sampleRate int32) []int32 {
+	if !r.IsValid() || sampleRate <= 0 {
+		return nil
+	}
+
+	cadence := make([]int32, r.cadenceLength(sampleRate))
+	for k := range cadence {
+		cadence[k] = int32(r.samplesUntil(sampleRate, int64(k)+1) - r.samplesUntil(sampleRate, int64(k)))
+	}
+
+	return cadence
+}
+
+// Get the number of frames before the number of samples per frame repeats.
+func (r FrameRate) cadenceLength(sampleRate int32) int64 {
+	return int64(r.N) / gcd(int64(r.N), int64(sampleRate)*int64(r.D))
+}
+
+// Get the number of samples before the start of a frame, rounded to the nearest sample.
+func (r FrameRate) samplesUntil(sampleRate int32, frame int64) int64 {
+	return (2*frame*int64(sampleRate)*int64(r.D) + int64(r.N)) / (2 * int64(r.N))
+}
+
+// Format the frame rate the way it is usually written, for instance "25", "29.97", "23.976" or "12500/1001".
+func (r FrameRate) String() string {
+	switch {
+	case r.D == 1:
+		return strconv.Itoa(int(r.N))
+	case r.D == 1001 && r.N%1000 == 0:
+		base := r.N / 1000
+		if base%24 == 0 {
+			return fmt.Sprintf("%.3f", r.Float64())
+		}
+		return fmt.Sprintf("%.2f", r.Float64())
+	default:
+		return fmt.Sprintf("%d/%d", r.N, r.D)
+	}
+}
+
+// Implements encoding.TextMarshaler, using the same format as String.
+func (r FrameRate) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// Implements encoding.TextUnmarshaler, accepting anything ParseFrameRate accepts.
+func (r *FrameRate) UnmarshalText(text []byte) error {
+	parsed, err := ParseFrameRate(string(text))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Parse a frame rate written as a rational number such as "60000/1001", or as a decimal number such as "25" or "59.94".
+// Decimal numbers that are close to a 1000/1001 rate, such as 29.97 or 23.98, are returned as the exact NTSC rate.
+// A trailing "p", "i" or "fps" is ignored.
+func ParseFrameRate(s string) (FrameRate, error) {
+	str := strings.ToLower(strings.TrimSpace(s))
+	for _, suffix := range []string{"fps", "p", "i"} {
+		str = strings.TrimSpace(strings.TrimSuffix(str, suffix))
+	}
+
+	if num, den, ok := strings.Cut(str, "/"); ok {
+		n, errN := strconv.ParseInt(strings.TrimSpace(num), 10, 32)
+		d, errD := strconv.ParseInt(strings.TrimSpace(den), 10, 32)
+		if errN != nil || errD != nil || n <= 0 || d <= 0 {
+			return FrameRate{}, fmt.Errorf("invalid frame rate %q", s)
+		}
+		return FrameRate{int32(n), int32(d)}, nil
+	}
+
+	f, err := strconv.ParseFloat(str, 64)
+	if err != nil || f <= 0 || f > math.MaxInt32/1000 {
+		return FrameRate{}, fmt.Errorf("invalid frame rate %q", s)
+	}
+
+	if f == math.Trunc(f) {
+		return FrameRate{int32(f), 1}, nil
+	}
+
+	// NTSC style rates
+	base := math.Round(f * 1.001)
+	if base >= 1 && math.Abs(f-base/1.001) < 0.006 {
+		if base*1000 > math.MaxInt32 {
+			return FrameRate{}, fmt.Errorf("invalid frame rate %q", s)
+		}
+		return FrameRate{int32(base) * 1000, 1001}, nil
+	}
+
+	// Any other decimal rate, using the number of decimals that was written
+	_, decimals, _ := strings.Cut(str, ".")
+	if len(decimals) > 18 {
+		return FrameRate{}, fmt.Errorf("invalid frame rate %q", s)
+	}
+	d := int64(math.Pow(10, float64(len(decimals))))
+	n := int64(math.Round(f * float64(d)))
+	if n <= 0 || n > math.MaxInt32 {
+		return FrameRate{}, fmt.Errorf("invalid frame rate %q", s)
+	}
+	g := gcd(n, d)
+	if d/g > math.MaxInt32 {
+		return FrameRate{}, fmt.Errorf("invalid frame rate %q", s)
+	}
+
+	return FrameRate{int32(n / g), int32(d / g)}, nil
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// Get the frame rate of the video frame.
+func (p *VideoFrameV2) GetFrameRate() FrameRate {
+	return FrameRate{p.FrameRateN, p.FrameRateD}
+}
+
+// Set the frame rate of the video frame.
+func (p *VideoFrameV2) SetFrameRate(r FrameRate) {
+	p.FrameRateN = r.N
+	p.FrameRateD = r.D
+}
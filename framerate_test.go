@@ -0,0 +1,68 @@
+package gondi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFrameRate(t *testing.T) {
+	tests := map[string]FrameRate{
+		"25":         FrameRate25,
+		"50p":        FrameRate50,
+		"59.94":      FrameRate5994,
+		"60000/1001": FrameRate5994,
+		"29.97":      FrameRate2997,
+		"23.976":     FrameRate2398,
+		"23.98":      FrameRate2398,
+		"12.5":       {25, 2},
+		"30 fps":     FrameRate30,
+		"0.004":      {1, 250},
+	}
+	for s, want := range tests {
+		got, err := ParseFrameRate(s)
+		if err != nil {
+			t.Errorf("ParseFrameRate(%q) failed: %v", s, err)
+		} else if got != want {
+			t.Errorf("ParseFrameRate(%q) returned %v, want %v", s, got, want)
+		}
+	}
+
+	for _, invalid := range []string{"", "abc", "0", "-25", "30/0", "1e-9", "0.2000000001", "2146853.147", "0.0000000000000000001"} {
+		if _, err := ParseFrameRate(invalid); err == nil {
+			t.Errorf("ParseFrameRate(%q) did not fail", invalid)
+		}
+	}
+
+	for _, r := range []FrameRate{FrameRate2398, FrameRate24, FrameRate25, FrameRate2997, FrameRate30, FrameRate50, FrameRate5994, FrameRate60} {
+		if parsed, err := ParseFrameRate(r.String()); err != nil || parsed != r {
+			t.Errorf("%v did not survive a round trip through String(), got %v", r, parsed)
+		}
+	}
+}
+
+func TestFrameRateCadence(t *testing.T) {
+	if got := FrameRate2997.AudioCadence(48000); !reflect.DeepEqual(got, []int32{1602, 1601, 1602, 1601, 1602}) {
+		t.Errorf("29.97 cadence at 48kHz is %v", got)
+	}
+	if got := FrameRate5994.AudioCadence(48000); !reflect.DeepEqual(got, []int32{801, 801, 800, 801, 801}) {
+		t.Errorf("59.94 cadence at 48kHz is %v", got)
+	}
+	if got := FrameRate25.AudioCadence(48000); !reflect.DeepEqual(got, []int32{1920}) {
+		t.Errorf("25 cadence at 48kHz is %v", got)
+	}
+
+	// One hour of 29.97 at 44.1kHz adds up to the exact number of samples
+	var total int64
+	frames := int64(FrameRate2997.N) * 3600 / int64(FrameRate2997.D)
+	for frame := int64(0); frame < frames; frame++ {
+		total += int64(FrameRate2997.SamplesPerFrame(44100, frame))
+	}
+	if want := frames * 44100 * 1001 / 30000; total < want-1 || total > want+1 {
+		t.Errorf("got %d samples in an hour, want %d", total, want)
+	}
+
+	if d := FrameRate2997.FrameDuration(); d != 33366666*time.Nanosecond {
+		t.Errorf("29.97 frame duration is %v", d)
+	}
+}
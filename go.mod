@@ -1,5 +1,5 @@
 module github.com/bitfocus/gondi
 
-go 1.18
+go 1.21
 
 require github.com/ebitengine/purego v0.3.2
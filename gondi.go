@@ -10,6 +10,7 @@ NDI® is a registered trademark of Vizrt Group.
 package gondi
 
 import (
+	"runtime"
 	"unsafe"
 )
 
@@ -31,6 +32,8 @@ func ConvertAudioFromInterleaved(pSrc *AudioFrameV2, pDst *AudioFrameV2) {
 	assertLibrary()
 
 	ndilib_util_audio_from_interleaved_32f_v2(uintptr(unsafe.Pointer(pSrc)), uintptr(unsafe.Pointer(pDst)))
+	runtime.KeepAlive(pSrc)
+	runtime.KeepAlive(pDst)
 }
 
 // If your want your audio frames to be interleaved, you can use this function to convert them from planar format.
@@ -39,11 +42,15 @@ func ConvertAudioToInterleaved(pSrc *AudioFrameV2, pDst *AudioFrameV2) {
 	assertLibrary()
 
 	ndilib_util_audio_to_interleaved_32f_v2(uintptr(unsafe.Pointer(pSrc)), uintptr(unsafe.Pointer(pDst)))
+	runtime.KeepAlive(pSrc)
+	runtime.KeepAlive(pDst)
 }
 
 // Allocate a new NDIMetadataFrame and initialize it with the specified utf-8 data string.
+// The frame holds a copy of the data, which stays alive for as long as the frame is referenced. The frame and its
+// data are pinned while the SDK reads them, and the SDK copies metadata when it is sent, so the frame may be dropped
+// as soon as the send call has returned.
 func NewMetadataFrame(data string) *MetadataFrame {
-	// I am afraid that the data parameter might be garbage collected before the C code is done with it, though.
 	ret := &MetadataFrame{
 		Length:   int32(len(data)),
 		Timecode: SendTimecodeSynthesize,
@@ -58,9 +65,17 @@ func (p *MetadataFrame) GetData() string {
 	return goString(uintptr(unsafe.Pointer(p.Data)))
 }
 
+// Allocate a new source object with the given name and address, see Source.Set.
+func NewSource(name string, address string) *Source {
+	source := &Source{}
+	source.Set(name, address)
+
+	return source
+}
+
 // Name of the source
 func (s *Source) Name() string {
-	if s.name == nil {
+	if s == nil || s.name == nil {
 		return ""
 	}
 	return goString(uintptr(unsafe.Pointer(s.name)))
@@ -68,16 +83,24 @@ func (s *Source) Name() string {
 
 // Address of the source
 func (s *Source) Address() string {
-	if s.address == nil {
+	if s == nil || s.address == nil {
 		return ""
 	}
 	return goString(uintptr(unsafe.Pointer(s.address)))
 }
 
-// Set the name and address of the source object
+// Set the name and address of the source object. An empty string is stored as NULL for the SDK.
+// The strings are copied and kept alive by the source object. They are pinned while the SDK reads them, and the SDK
+// copies them when the source is used.
 func (s *Source) Set(name string, address string) {
-	s.name = cString(name)
-	s.address = cString(address)
+	s.name = nil
+	s.address = nil
+	if name != "" {
+		s.name = cString(name)
+	}
+	if address != "" {
+		s.address = cString(address)
+	}
 }
 
 // Get the audio frames as an array of float32
@@ -99,6 +122,8 @@ func (p *AudioFrameV2) GetInterleavedArray() []float32 {
 	}
 
 	ndilib_util_audio_to_interleaved_32f_v2(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(tempFrame)))
+	runtime.KeepAlive(p)
+	runtime.KeepAlive(tempFrame)
 
 	return dst
 }
@@ -116,6 +141,8 @@ func (p *AudioFrameV2) SetFromInterleavedArray(audio []float32) {
 		Data:        &audio[0],
 	}
 	ndilib_util_audio_from_interleaved_32f_v2(uintptr(unsafe.Pointer(tempFrame)), uintptr(unsafe.Pointer(p)))
+	runtime.KeepAlive(tempFrame)
+	runtime.KeepAlive(p)
 }
 
 // Set the audio frames from an array of float32
@@ -17,7 +17,7 @@ var (
 	ndilib_util_audio_from_interleaved_32f_v2 func(src uintptr, dst uintptr)
 	ndilib_util_audio_to_interleaved_32f_v2   func(src uintptr, dst uintptr)
 
-	ndilib_send_create_v2                 func(settings uintptr) uintptr
+	ndilib_send_create_v2                 func(settings uintptr, config uintptr) uintptr
 	ndilib_send_destroy                   func(instance uintptr)
 	ndilib_send_send_video_v2             func(instance uintptr, frame uintptr)
 	ndilib_send_send_video_async_v2       func(instance uintptr, frame uintptr)
@@ -30,6 +30,7 @@ var (
 	ndilib_send_clear_connection_metadata func(instance uintptr)
 	ndilib_send_set_failover              func(instance uintptr, source uintptr)
 	ndilib_send_get_no_connections        func(instance uintptr, timeout uint32) int32
+	ndilib_send_get_source_name           func(instance uintptr) uintptr
 
 	ndilib_find_create_v2           func(settings uintptr) uintptr
 	ndilib_find_destroy             func(instance uintptr)
@@ -38,20 +39,29 @@ var (
 
 	ndilib_recv_create_v3                 func(settings uintptr) uintptr
 	ndilib_recv_destroy                   func(instance uintptr)
+	ndilib_recv_connect                   func(instance uintptr, source uintptr)
 	ndilib_recv_free_video_v2             func(instance uintptr, frame uintptr)
 	ndilib_recv_free_audio_v2             func(instance uintptr, frame uintptr)
 	ndilib_recv_free_metadata             func(instance uintptr, frame uintptr)
 	ndilib_recv_capture_v2                func(instance uintptr, videoFrame uintptr, audioFrame uintptr, metadataFrame uintptr, timeout uint32) int32
 	ndilib_recv_get_performance           func(instance uintptr, total uintptr, dropped uintptr)
+	ndilib_recv_get_queue                 func(instance uintptr, total uintptr)
 	ndilib_recv_set_tally                 func(instance uintptr, tally uintptr) bool
 	ndilib_recv_send_metadata             func(instance uintptr, metadata uintptr) bool
 	ndilib_recv_add_connection_metadata   func(instance uintptr, metadata uintptr) bool
 	ndilib_recv_clear_connection_metadata func(instance uintptr)
+	ndilib_recv_get_no_connections        func(instance uintptr) int32
+	ndilib_recv_get_web_control           func(instance uintptr) uintptr
+	ndilib_recv_free_string               func(instance uintptr, str uintptr)
+	ndilib_recv_ptz_is_supported          func(instance uintptr) bool
 
 	ndilib_routing_create  func(settings uintptr) uintptr
 	ndilib_routing_destroy func(instance uintptr)
 	ndilib_routing_change  func(instance uintptr, source uintptr) bool
 	ndilib_routing_clear   func(instance uintptr) bool
+
+	ndilib_routing_get_no_connections func(instance uintptr, timeout uint32) int32
+	ndilib_routing_get_source_name    func(instance uintptr) uintptr
 )
 
 // Windows is not supported by go-purego
@@ -110,6 +120,7 @@ func InitLibrary(libraryPath string) error {
 		purego.RegisterLibFunc(&ndilib_send_clear_connection_metadata, ndi_shared_library, "NDIlib_send_clear_connection_metadata")
 		purego.RegisterLibFunc(&ndilib_send_set_failover, ndi_shared_library, "NDIlib_send_set_failover")
 		purego.RegisterLibFunc(&ndilib_send_get_no_connections, ndi_shared_library, "NDIlib_send_get_no_connections")
+		purego.RegisterLibFunc(&ndilib_send_get_source_name, ndi_shared_library, "NDIlib_send_get_source_name")
 
 		purego.RegisterLibFunc(&ndilib_find_create_v2, ndi_shared_library, "NDIlib_find_create_v2")
 		purego.RegisterLibFunc(&ndilib_find_get_current_sources, ndi_shared_library, "NDIlib_find_get_current_sources")
@@ -118,20 +129,28 @@ func InitLibrary(libraryPath string) error {
 
 		purego.RegisterLibFunc(&ndilib_recv_create_v3, ndi_shared_library, "NDIlib_recv_create_v3")
 		purego.RegisterLibFunc(&ndilib_recv_destroy, ndi_shared_library, "NDIlib_recv_destroy")
+		purego.RegisterLibFunc(&ndilib_recv_connect, ndi_shared_library, "NDIlib_recv_connect")
 		purego.RegisterLibFunc(&ndilib_recv_free_metadata, ndi_shared_library, "NDIlib_recv_free_metadata")
 		purego.RegisterLibFunc(&ndilib_recv_free_video_v2, ndi_shared_library, "NDIlib_recv_free_video_v2")
 		purego.RegisterLibFunc(&ndilib_recv_free_audio_v2, ndi_shared_library, "NDIlib_recv_free_audio_v2")
 		purego.RegisterLibFunc(&ndilib_recv_capture_v2, ndi_shared_library, "NDIlib_recv_capture_v2")
 		purego.RegisterLibFunc(&ndilib_recv_get_performance, ndi_shared_library, "NDIlib_recv_get_performance")
+		purego.RegisterLibFunc(&ndilib_recv_get_queue, ndi_shared_library, "NDIlib_recv_get_queue")
 		purego.RegisterLibFunc(&ndilib_recv_set_tally, ndi_shared_library, "NDIlib_recv_set_tally")
 		purego.RegisterLibFunc(&ndilib_recv_send_metadata, ndi_shared_library, "NDIlib_recv_send_metadata")
 		purego.RegisterLibFunc(&ndilib_recv_add_connection_metadata, ndi_shared_library, "NDIlib_recv_add_connection_metadata")
 		purego.RegisterLibFunc(&ndilib_recv_clear_connection_metadata, ndi_shared_library, "NDIlib_recv_clear_connection_metadata")
+		purego.RegisterLibFunc(&ndilib_recv_get_no_connections, ndi_shared_library, "NDIlib_recv_get_no_connections")
+		purego.RegisterLibFunc(&ndilib_recv_get_web_control, ndi_shared_library, "NDIlib_recv_get_web_control")
+		purego.RegisterLibFunc(&ndilib_recv_free_string, ndi_shared_library, "NDIlib_recv_free_string")
+		purego.RegisterLibFunc(&ndilib_recv_ptz_is_supported, ndi_shared_library, "NDIlib_recv_ptz_is_supported")
 
 		purego.RegisterLibFunc(&ndilib_routing_create, ndi_shared_library, "NDIlib_routing_create")
 		purego.RegisterLibFunc(&ndilib_routing_destroy, ndi_shared_library, "NDIlib_routing_destroy")
 		purego.RegisterLibFunc(&ndilib_routing_change, ndi_shared_library, "NDIlib_routing_change")
 		purego.RegisterLibFunc(&ndilib_routing_clear, ndi_shared_library, "NDIlib_routing_clear")
+		purego.RegisterLibFunc(&ndilib_routing_get_no_connections, ndi_shared_library, "NDIlib_routing_get_no_connections")
+		purego.RegisterLibFunc(&ndilib_routing_get_source_name, ndi_shared_library, "NDIlib_routing_get_source_name")
 
 		result := ndilib_load()
 		if result == 0 {
@@ -0,0 +1,23 @@
+package gondi
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// Stand in for a function of the SDK until the test ends, so the logic around it can be tested without the library.
+// The library is reported as loaded meanwhile.
+func stubLibrary[F any](t *testing.T, fn *F, stub F) {
+	savedLibrary, saved := ndi_shared_library, *fn
+	t.Cleanup(func() {
+		ndi_shared_library, *fn = savedLibrary, saved
+	})
+	ndi_shared_library = 1
+	*fn = stub
+}
+
+// Get the struct the SDK is passed a pointer to.
+func stubArg[T any](ptr uintptr) *T {
+	// We take the address and then dereference it to trick go vet from creating a possible misuse of unsafe.Pointer
+	return (*T)(*(*unsafe.Pointer)(unsafe.Pointer(&ptr)))
+}
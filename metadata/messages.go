@@ -0,0 +1,135 @@
+package metadata
+
+import "encoding/xml"
+
+// Identifies the product behind a source or receiver. It is usually sent as connection metadata.
+type Product struct {
+	XMLName      xml.Name `xml:"ndi_product"`
+	LongName     string   `xml:"long_name,attr,omitempty"`
+	ShortName    string   `xml:"short_name,attr,omitempty"`
+	Manufacturer string   `xml:"manufacturer,attr,omitempty"`
+	Version      string   `xml:"version,attr,omitempty"`
+	Session      string   `xml:"session,attr,omitempty"`
+	ModelName    string   `xml:"model_name,attr,omitempty"`
+	Serial       string   `xml:"serial,attr,omitempty"`
+}
+
+// Announces what a source supports. It is usually sent as connection metadata. The web control URL may
+// contain %IP%, which receivers replace with the address of the source.
+type Capabilities struct {
+	XMLName      xml.Name `xml:"ndi_capabilities"`
+	WebControl   string   `xml:"web_control,attr,omitempty"`
+	PTZ          bool     `xml:"ntk_ptz,attr,omitempty"`
+	PanTilt      bool     `xml:"ntk_pan_tilt,attr,omitempty"`
+	Zoom         bool     `xml:"ntk_zoom,attr,omitempty"`
+	Iris         bool     `xml:"ntk_iris,attr,omitempty"`
+	WhiteBalance bool     `xml:"ntk_white_balance,attr,omitempty"`
+	Exposure     bool     `xml:"ntk_exposure,attr,omitempty"`
+	ExposureV2   bool     `xml:"ntk_exposure_v2,attr,omitempty"`
+	Focus        bool     `xml:"ntk_focus,attr,omitempty"`
+	AutoFocus    bool     `xml:"ntk_autofocus,attr,omitempty"`
+	Record       bool     `xml:"ntk_record,attr,omitempty"`
+	KVM          bool     `xml:"ntk_kvm,attr,omitempty"`
+}
+
+// Reports the tally state of a source back to its receivers, so that for instance a camera operator can see it.
+type TallyEcho struct {
+	XMLName   xml.Name `xml:"ndi_tally_echo"`
+	OnProgram bool     `xml:"on_program,attr"`
+	OnPreview bool     `xml:"on_preview,attr"`
+}
+
+// Describes the video and audio format a source is sending, or a receiver is asking for.
+type Format struct {
+	XMLName xml.Name     `xml:"ndi_format"`
+	Video   *VideoFormat `xml:"video,omitempty"`
+	Audio   *AudioFormat `xml:"audio,omitempty"`
+}
+
+type VideoFormat struct {
+	Xres        int32   `xml:"xres,attr,omitempty"`
+	Yres        int32   `xml:"yres,attr,omitempty"`
+	FrameRateN  int32   `xml:"frame_rate_n,attr,omitempty"`
+	FrameRateD  int32   `xml:"frame_rate_d,attr,omitempty"`
+	AspectRatio float32 `xml:"aspect_ratio,attr,omitempty"`
+	Progressive bool    `xml:"progressive,attr"`
+	FourCC      string  `xml:"fourcc,attr,omitempty"`
+}
+
+type AudioFormat struct {
+	SampleRate  int32 `xml:"sample_rate,attr,omitempty"`
+	NumChannels int32 `xml:"no_channels,attr,omitempty"`
+}
+
+// Zoom to an absolute value, from 0.0 (zoomed in) to 1.0 (zoomed out).
+type PTZZoom struct {
+	XMLName xml.Name `xml:"ntk_ptz_zoom"`
+	Zoom    float32  `xml:"zoom,attr"`
+}
+
+// Zoom at a speed, from -1.0 (zoom out) to 1.0 (zoom in), 0 stops.
+type PTZZoomSpeed struct {
+	XMLName   xml.Name `xml:"ntk_ptz_zoom_speed"`
+	ZoomSpeed float32  `xml:"zoom_speed,attr"`
+}
+
+// Move to an absolute position, from -1.0 to 1.0 for both pan (left to right) and tilt (down to up).
+type PTZPanTilt struct {
+	XMLName xml.Name `xml:"ntk_ptz_pan_tilt"`
+	Pan     float32  `xml:"pan,attr"`
+	Tilt    float32  `xml:"tilt,attr"`
+}
+
+// Move at a speed, from -1.0 to 1.0 for both pan (left to right) and tilt (down to up), 0 stops.
+type PTZPanTiltSpeed struct {
+	XMLName   xml.Name `xml:"ntk_ptz_pan_tilt_speed"`
+	PanSpeed  float32  `xml:"pan_speed,attr"`
+	TiltSpeed float32  `xml:"tilt_speed,attr"`
+}
+
+// Store the current position as a preset, from 0 to 99.
+type PTZStorePreset struct {
+	XMLName xml.Name `xml:"ntk_ptz_store_preset"`
+	Index   int      `xml:"index,attr"`
+}
+
+// Recall a preset, from 0 to 99, at a speed from 0.0 to 1.0.
+type PTZRecallPreset struct {
+	XMLName xml.Name `xml:"ntk_ptz_recall_preset"`
+	Index   int      `xml:"index,attr"`
+	Speed   float32  `xml:"speed,attr"`
+}
+
+// Enable auto focus.
+type PTZAutoFocus struct {
+	XMLName xml.Name `xml:"ntk_ptz_auto_focus"`
+}
+
+// Set the focus mode, "auto" or "manual". In manual mode, the distance is from 0.0 (near) to 1.0 (far).
+type PTZFocus struct {
+	XMLName  xml.Name `xml:"ntk_ptz_focus"`
+	Mode     string   `xml:"mode,attr"`
+	Distance float32  `xml:"distance,attr"`
+}
+
+// Focus at a speed, from -1.0 (nearer) to 1.0 (further), 0 stops.
+type PTZFocusSpeed struct {
+	XMLName    xml.Name `xml:"ntk_ptz_focus_speed"`
+	FocusSpeed float32  `xml:"focus_speed,attr"`
+}
+
+// Set the white balance mode, "auto", "indoor", "outdoor", "one_push" or "manual".
+// In manual mode, red and blue are from 0.0 to 1.0.
+type PTZWhiteBalance struct {
+	XMLName xml.Name `xml:"ntk_ptz_white_balance"`
+	Mode    string   `xml:"mode,attr"`
+	Red     float32  `xml:"red,attr"`
+	Blue    float32  `xml:"blue,attr"`
+}
+
+// Set the exposure mode, "auto" or "manual". In manual mode, the value is from 0.0 (dark) to 1.0 (light).
+type PTZExposure struct {
+	XMLName xml.Name `xml:"ntk_ptz_exposure"`
+	Mode    string   `xml:"mode,attr"`
+	Value   float32  `xml:"value,attr"`
+}
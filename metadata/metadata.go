@@ -0,0 +1,130 @@
+/*
+Package metadata provides Go types for the standard NDI metadata XML messages, such as product information,
+capabilities, tally echo and PTZ commands, and functions to encode and decode them.
+
+Any type that can be marshalled with encoding/xml can be sent as metadata. Decode returns the registered type
+matching the root element of a message, and Register adds custom message types.
+*/
+package metadata
+
+import (
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// A metadata message with a root element that has no registered type.
+type Unknown struct {
+	// The name of the root element.
+	Root string
+
+	// The complete XML data of the message.
+	Data string
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]reflect.Type{}
+)
+
+func init() {
+	for _, v := range []any{
+		Product{},
+		Capabilities{},
+		TallyEcho{},
+		Format{},
+		PTZZoom{},
+		PTZZoomSpeed{},
+		PTZPanTilt{},
+		PTZPanTiltSpeed{},
+		PTZStorePreset{},
+		PTZRecallPreset{},
+		PTZAutoFocus{},
+		PTZFocus{},
+		PTZFocusSpeed{},
+		PTZWhiteBalance{},
+		PTZExposure{},
+	} {
+		Register(v)
+	}
+}
+
+// Register a message type for Decode. The type must be a struct with an XMLName field tagged with
+// the name of its root element, like the types of this package. Registering a root element again
+// replaces the previous type.
+func Register(v any) {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	field, ok := t.FieldByName("XMLName")
+	if t.Kind() != reflect.Struct || !ok {
+		panic("metadata types must be structs with an XMLName field")
+	}
+	root, _, _ := strings.Cut(field.Tag.Get("xml"), ",")
+	if root == "" {
+		panic("the XMLName field of a metadata type must be tagged with the root element name")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry[root] = t
+}
+
+// Encode a message as an XML string. A string is returned as it is, so raw XML can be passed through, and so is the
+// data of an Unknown message, so anything returned by Decode encodes back to the same message.
+func Encode(v any) (string, error) {
+	switch m := v.(type) {
+	case string:
+		return m, nil
+	case *Unknown:
+		return m.Data, nil
+	case Unknown:
+		return m.Data, nil
+	}
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Decode an XML message into a pointer to the registered type of its root element, for instance *Product for
+// <ndi_product>. Messages with an unregistered root element are returned as *Unknown.
+func Decode(data string) (any, error) {
+	root := Root(data)
+	if root == "" {
+		return nil, errors.New("metadata is not an XML document")
+	}
+
+	registryMu.RLock()
+	t, ok := registry[root]
+	registryMu.RUnlock()
+	if !ok {
+		return &Unknown{Root: root, Data: data}, nil
+	}
+
+	v := reflect.New(t).Interface()
+	if err := xml.Unmarshal([]byte(data), v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// Get the name of the root element of an XML message, or an empty string if there is none.
+func Root(data string) string {
+	decoder := xml.NewDecoder(strings.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local
+		}
+	}
+}
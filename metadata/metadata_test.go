@@ -0,0 +1,161 @@
+package metadata
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGolden(t *testing.T) {
+	tests := map[string]any{
+		"ndi_product.xml": &Product{
+			XMLName:      xml.Name{Local: "ndi_product"},
+			LongName:     "NDILib Send Example.",
+			ShortName:    "NDILib Send",
+			Manufacturer: "CoolCo, inc.",
+			Version:      "1.000.000",
+			Session:      "default",
+			ModelName:    "PBX-1",
+			Serial:       "ABCDEFG",
+		},
+		"ndi_capabilities.xml": &Capabilities{
+			XMLName:      xml.Name{Local: "ndi_capabilities"},
+			WebControl:   "http://%IP%/",
+			PTZ:          true,
+			PanTilt:      true,
+			Zoom:         true,
+			Iris:         true,
+			WhiteBalance: true,
+			Exposure:     true,
+			Record:       true,
+		},
+		"ndi_capabilities_kvm.xml": &Capabilities{
+			XMLName: xml.Name{Local: "ndi_capabilities"},
+			KVM:     true,
+		},
+		"ndi_tally_echo.xml": &TallyEcho{
+			XMLName:   xml.Name{Local: "ndi_tally_echo"},
+			OnProgram: true,
+		},
+		"ndi_format.xml": &Format{
+			XMLName: xml.Name{Local: "ndi_format"},
+			Video:   &VideoFormat{Xres: 1920, Yres: 1080, FrameRateN: 60000, FrameRateD: 1001, AspectRatio: 16.0 / 9.0, Progressive: true, FourCC: "UYVY"},
+			Audio:   &AudioFormat{SampleRate: 48000, NumChannels: 2},
+		},
+		"ntk_ptz_pan_tilt_speed.xml": &PTZPanTiltSpeed{
+			XMLName:   xml.Name{Local: "ntk_ptz_pan_tilt_speed"},
+			PanSpeed:  -0.5,
+			TiltSpeed: 0.25,
+		},
+		"ntk_ptz_recall_preset.xml": &PTZRecallPreset{
+			XMLName: xml.Name{Local: "ntk_ptz_recall_preset"},
+			Index:   3,
+			Speed:   1,
+		},
+		"ntk_ptz_focus.xml": &PTZFocus{
+			XMLName:  xml.Name{Local: "ntk_ptz_focus"},
+			Mode:     "manual",
+			Distance: 0.75,
+		},
+	}
+
+	for file, want := range tests {
+		data, err := os.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := Decode(string(data))
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: decoded %+v, want %+v", file, got, want)
+			continue
+		}
+
+		// Encoding and decoding again gives the same message
+		encoded, err := Encode(got)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		again, err := Decode(encoded)
+		if err != nil || !reflect.DeepEqual(again, want) {
+			t.Errorf("%s: %s did not decode to the same message: %+v, %v", file, encoded, again, err)
+		}
+	}
+}
+
+func TestDecodeUnknown(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "ntk_kvm.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Decode(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown, ok := got.(*Unknown)
+	if !ok || unknown.Root != "ntk_kvm" || unknown.Data != string(data) {
+		t.Errorf("decoded %#v, want an Unknown ntk_kvm message", got)
+	}
+	if encoded, err := Encode(got); err != nil || encoded != string(data) {
+		t.Errorf("encoded the Unknown message as %q, %v, want the data unchanged", encoded, err)
+	}
+
+	if _, err := Decode("not xml"); err == nil {
+		t.Error("decoding invalid XML did not fail")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	type KVM struct {
+		XMLName xml.Name `xml:"ntk_kvm"`
+		U       string   `xml:"u,attr"`
+	}
+	Register(KVM{})
+	defer func() {
+		registryMu.Lock()
+		delete(registry, "ntk_kvm")
+		registryMu.Unlock()
+	}()
+
+	got, err := Decode(`<ntk_kvm u="01234567"/>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kvm, ok := got.(*KVM); !ok || kvm.U != "01234567" {
+		t.Errorf("decoded %#v, want a registered KVM message", got)
+	}
+}
+
+func TestZeroValues(t *testing.T) {
+	// The nearest focus, the darkest exposure and a white balance of 0 are valid values, and must be sent
+	tests := map[string]any{
+		`distance="0"`: &PTZFocus{XMLName: xml.Name{Local: "ntk_ptz_focus"}, Mode: "manual"},
+		`red="0"`:      &PTZWhiteBalance{XMLName: xml.Name{Local: "ntk_ptz_white_balance"}, Mode: "manual", Blue: 0.5},
+		`blue="0"`:     &PTZWhiteBalance{XMLName: xml.Name{Local: "ntk_ptz_white_balance"}, Mode: "manual", Red: 0.5},
+		`value="0"`:    &PTZExposure{XMLName: xml.Name{Local: "ntk_ptz_exposure"}, Mode: "manual"},
+	}
+
+	for attr, want := range tests {
+		encoded, err := Encode(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(encoded, attr) {
+			t.Errorf("%s does not contain %s", encoded, attr)
+		}
+
+		got, err := Decode(encoded)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s decoded to %+v, %v", encoded, got, err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package gondi
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/bitfocus/gondi/metadata"
+)
+
+// How long a single metadata capture waits, this bounds how long Destroy waits for the capture loop.
+const metadataCaptureTimeoutMs = 100
+
+// A metadata message received from a connected receiver. The data is copied into Go memory, so the message
+// can be kept for as long as needed.
+type MetadataMessage struct {
+	// The UTF-8 XML data of the message.
+	Data string
+
+	// The name of the root XML element, or empty if the data is not valid XML.
+	Root string
+
+	// The timecode of the metadata frame.
+	Timecode Timecode
+
+	// The time the message was captured.
+	Time time.Time
+}
+
+// Decode the message into a type from the metadata package, see metadata.Decode.
+func (m MetadataMessage) Decode() (any, error) {
+	return metadata.Decode(m.Data)
+}
+
+type metadataHandler struct {
+	root string
+	fn   func(MetadataMessage)
+}
+
+// Registered handlers and watchers for the metadata capture loop of a sender.
+type metadataRouter struct {
+	mu       sync.Mutex
+	handlers []*metadataHandler
+	watchers subscribers[MetadataMessage]
+}
+
+// Watch metadata sent to this sender by connected receivers. Every message is delivered on the returned channel,
+// which is closed when the context is done or the instance is destroyed. If the channel is not read fast enough,
+// the oldest messages are dropped for this watcher.
+//
+// Watchers and handlers share a single goroutine capturing metadata, so SendInstance.Capture should not be used
+// at the same time, as each message is only returned once by the SDK. The goroutine stops once there are no
+// watchers and no handlers left.
+func (p *SendInstance) WatchMetadata(ctx context.Context) <-chan MetadataMessage {
+	assertLibrary()
+
+	// Added under the lock, so the capture goroutine cannot stop for being idle right before it is started here
+	p.metadata.mu.Lock()
+	ch := p.metadata.watchers.add(ctx, 64, nil)
+	p.metadata.mu.Unlock()
+	p.workers.start("metadata", p.captureMetadata)
+
+	return ch
+}
+
+// Register a handler for metadata messages with the given XML root element name, for instance "ntk_kvm".
+// An empty root matches all messages. The handler is called on the capture goroutine, so it should return quickly.
+// The handler may close the sender, closing then returns without waiting for the handler itself.
+// Call the returned function to remove the handler.
+func (p *SendInstance) HandleMetadata(root string, handler func(MetadataMessage)) (remove func()) {
+	assertLibrary()
+
+	h := &metadataHandler{root, handler}
+
+	p.metadata.mu.Lock()
+	p.metadata.handlers = append(p.metadata.handlers, h)
+	p.metadata.mu.Unlock()
+
+	p.workers.start("metadata", p.captureMetadata)
+
+	return func() {
+		p.metadata.mu.Lock()
+		defer p.metadata.mu.Unlock()
+
+		for i, registered := range p.metadata.handlers {
+			if registered == h {
+				p.metadata.handlers = append(p.metadata.handlers[:i], p.metadata.handlers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (p *SendInstance) captureMetadata(stop <-chan struct{}) {
+	// Allocated on the heap, as the SDK writes to it
+	frame := new(MetadataFrame)
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if p.metadata.stopIfIdle(&p.workers) {
+			return
+		}
+
+		if !p.state.acquire() {
+			return
+		}
+		frameType := FrameType(ndilib_send_capture(p.ndiInstance, uintptr(unsafe.Pointer(frame)), metadataCaptureTimeoutMs))
+		runtime.KeepAlive(frame)
+		if frameType != FrameTypeMetadata {
+			p.state.release()
+			continue
+		}
+
+		msg := MetadataMessage{
+			Data:     frame.GetData(),
+			Timecode: frame.GetTimecode(),
+			Time:     time.Now(),
+		}
+		ndilib_send_free_metadata(p.ndiInstance, uintptr(unsafe.Pointer(frame)))
+		p.state.release()
+		msg.Root = metadata.Root(msg.Data)
+
+		p.workers.callback(stop, func() { p.metadata.dispatch(msg) })
+	}
+}
+
+// Stop the capture worker when there is nobody left to deliver messages to. The check and the stop are done under
+// the lock, so a handler or watcher added meanwhile starts a new worker instead of being left without one.
+func (r *metadataRouter) stopIfIdle(w *workers) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.handlers) > 0 || r.watchers.any() {
+		return false
+	}
+	w.stop("metadata")
+	return true
+}
+
+func (r *metadataRouter) dispatch(msg MetadataMessage) {
+	r.mu.Lock()
+	handlers := make([]*metadataHandler, 0, len(r.handlers))
+	for _, h := range r.handlers {
+		if h.root == "" || h.root == msg.Root {
+			handlers = append(handlers, h)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, h := range handlers {
+		h.fn(msg)
+	}
+	r.watchers.publish(msg)
+}
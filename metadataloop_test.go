@@ -0,0 +1,100 @@
+package gondi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bitfocus/gondi/metadata"
+)
+
+func TestMetadataDispatch(t *testing.T) {
+	var router metadataRouter
+	var kvm, all int
+	router.handlers = []*metadataHandler{
+		{"ntk_kvm", func(msg MetadataMessage) { kvm++ }},
+		{"", func(msg MetadataMessage) { all++ }},
+	}
+
+	for _, data := range []string{
+		`<?xml version="1.0"?><ntk_kvm u="01234"/>`,
+		`<ndi_tally_echo on_program="true" on_preview="false"/>`,
+		`not xml`,
+	} {
+		router.dispatch(MetadataMessage{Data: data, Root: metadata.Root(data)})
+	}
+
+	if kvm != 1 || all != 3 {
+		t.Errorf("handlers were called %d and %d times, want 1 and 3", kvm, all)
+	}
+}
+
+// Stand in for the SDK with a sender that receives the same metadata over and over.
+func stubMetadataSender(t *testing.T) *SendInstance {
+	data := []byte("<ntk_kvm u=\"01234\"/>\x00")
+	stubLibrary(t, &ndilib_send_capture, func(instance uintptr, frame uintptr, timeout uint32) int32 {
+		time.Sleep(time.Millisecond)
+		stubArg[MetadataFrame](frame).Data = &data[0]
+		return int32(FrameTypeMetadata)
+	})
+	stubLibrary(t, &ndilib_send_free_metadata, func(instance uintptr, frame uintptr) {})
+	stubLibrary(t, &ndilib_send_destroy, func(instance uintptr) {})
+
+	return &SendInstance{}
+}
+
+// Wait for the capture worker to stop, as it only notices that it is idle between two captures.
+func waitMetadataStopped(t *testing.T, p *SendInstance) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); p.workers.isRunning("metadata"); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the capture worker is still running without handlers or watchers")
+		}
+	}
+}
+
+func TestMetadataCaptureStopsWhenIdle(t *testing.T) {
+	p := stubMetadataSender(t)
+	defer p.Close()
+
+	received := make(chan MetadataMessage, 1)
+	remove := p.HandleMetadata("ntk_kvm", func(msg MetadataMessage) {
+		select {
+		case received <- msg:
+		default:
+		}
+	})
+	if msg := <-received; msg.Root != "ntk_kvm" {
+		t.Errorf("received %+v", msg)
+	}
+	remove()
+	waitMetadataStopped(t, p)
+
+	// A watcher starts it again, and it stops once the watcher is gone
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := p.WatchMetadata(ctx)
+	if msg := <-ch; msg.Root != "ntk_kvm" {
+		t.Errorf("watched %+v", msg)
+	}
+	cancel()
+	waitMetadataStopped(t, p)
+}
+
+func TestMetadataHandlerCloses(t *testing.T) {
+	p := stubMetadataSender(t)
+
+	closed := make(chan struct{})
+	p.HandleMetadata("", func(msg MetadataMessage) {
+		p.Close()
+		close(closed)
+	})
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("closing from a handler deadlocked")
+	}
+	if !p.IsClosed() {
+		t.Error("the sender was not closed")
+	}
+}
@@ -0,0 +1,317 @@
+/*
+Package metrics exposes the state of gondi receivers and senders as Prometheus metrics, in the text exposition
+format, without depending on a Prometheus client library.
+
+A Collector is an http.Handler that can be served on its own, for instance on /metrics. Instances are added with
+AddReceiver and AddSender, and are removed again as soon as they are closed, or when the returned function is called.
+All metrics are labelled with the name of the source.
+*/
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bitfocus/gondi"
+)
+
+// The content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Collects metrics from a set of receivers and senders. It is safe to use from multiple goroutines.
+type Collector struct {
+	mu        sync.Mutex
+	receivers map[string]*gondi.RecvInstance
+	senders   map[string]*gondi.SendInstance
+}
+
+// Create an empty collector.
+func NewCollector() *Collector {
+	return &Collector{
+		receivers: make(map[string]*gondi.RecvInstance),
+		senders:   make(map[string]*gondi.SendInstance),
+	}
+}
+
+// Add a receiver, labelled with the name of the source it receives. Each source can only be added once.
+// The receiver is removed when it is closed, or when the returned function is called.
+func (c *Collector) AddReceiver(source string, recv *gondi.RecvInstance) (remove func(), err error) {
+	if source == "" {
+		return nil, errors.New("source name is empty")
+	}
+	if recv.IsClosed() {
+		return nil, gondi.ErrClosed
+	}
+
+	c.mu.Lock()
+	if _, ok := c.receivers[source]; ok {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("a receiver of %q has already been added", source)
+	}
+	c.receivers[source] = recv
+	c.mu.Unlock()
+
+	// Registered without the lock held, as the callback runs right away if the receiver has been closed meanwhile
+	stop := recv.OnClose(func() { c.removeReceiver(source, recv) })
+
+	return func() {
+		stop()
+		c.removeReceiver(source, recv)
+	}, nil
+}
+
+// Remove a receiver, unless the source has been added again with another instance.
+func (c *Collector) removeReceiver(source string, recv *gondi.RecvInstance) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.receivers[source] == recv {
+		delete(c.receivers, source)
+	}
+}
+
+// Add a sender, labelled with its full source name as receivers see it. The sender is removed when it is closed,
+// or when the returned function is called.
+func (c *Collector) AddSender(send *gondi.SendInstance) (remove func(), err error) {
+	source, err := send.SourceName()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.addSender(source.Name(), send)
+}
+
+func (c *Collector) addSender(source string, send *gondi.SendInstance) (func(), error) {
+	if source == "" {
+		return nil, errors.New("source name is empty")
+	}
+	if send.IsClosed() {
+		return nil, gondi.ErrClosed
+	}
+
+	c.mu.Lock()
+	if _, ok := c.senders[source]; ok {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("a sender named %q has already been added", source)
+	}
+	c.senders[source] = send
+	c.mu.Unlock()
+
+	stop := send.OnClose(func() { c.removeSender(source, send) })
+
+	return func() {
+		stop()
+		c.removeSender(source, send)
+	}, nil
+}
+
+func (c *Collector) removeSender(source string, send *gondi.SendInstance) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.senders[source] == send {
+		delete(c.senders, source)
+	}
+}
+
+// A metric with all its samples, written as one block with its HELP and TYPE lines.
+type family struct {
+	name, kind, help string
+	samples          []sample
+}
+
+type sample struct {
+	labels string
+	value  float64
+}
+
+func (f *family) add(value float64, labels ...string) {
+	f.samples = append(f.samples, sample{formatLabels(labels), value})
+}
+
+// Format label name and value pairs, escaping the values as the exposition format requires.
+func formatLabels(pairs []string) string {
+	var b strings.Builder
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, pairs[i], escape.Replace(pairs[i+1]))
+	}
+
+	return b.String()
+}
+
+func boolValue(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// The polled state of a receiver.
+type receiverStats struct {
+	total, dropped gondi.RecvPerformance
+	queue          gondi.RecvQueue
+	connections    int32
+	tally          gondi.Tally
+}
+
+// The polled state of a sender.
+type senderStats struct {
+	sent        gondi.SendPerformance
+	connections int32
+	tally       gondi.Tally
+}
+
+// Poll all instances, and return the metrics in the order they are written. Instances that are closed while they are
+// polled are left out, as the SDK calls return zeros for them.
+func (c *Collector) collect() []*family {
+	c.mu.Lock()
+	receivers := make(map[string]*gondi.RecvInstance, len(c.receivers))
+	for source, recv := range c.receivers {
+		receivers[source] = recv
+	}
+	senders := make(map[string]*gondi.SendInstance, len(c.senders))
+	for source, send := range c.senders {
+		senders[source] = send
+	}
+	c.mu.Unlock()
+
+	recvStats := make(map[string]receiverStats, len(receivers))
+	for source, recv := range receivers {
+		total, dropped := recv.GetPerformance()
+		stats := receiverStats{
+			total:       *total,
+			dropped:     *dropped,
+			queue:       *recv.GetQueue(),
+			connections: recv.GetNumberOfConnections(),
+			tally:       recv.CurrentTally(),
+		}
+		if !recv.IsClosed() {
+			recvStats[source] = stats
+		}
+	}
+
+	sendStats := make(map[string]senderStats, len(senders))
+	for source, send := range senders {
+		stats := senderStats{
+			sent:        *send.GetPerformance(),
+			connections: send.GetNumberOfConnections(0),
+			tally:       send.CurrentTally(),
+		}
+		if !send.IsClosed() {
+			sendStats[source] = stats
+		}
+	}
+
+	return families(recvStats, sendStats)
+}
+
+// Turn the polled state of the instances into metrics, in the order they are written.
+func families(receivers map[string]receiverStats, senders map[string]senderStats) []*family {
+	recvFrames := &family{name: "gondi_recv_frames_total", kind: "counter", help: "Frames received by type."}
+	recvDropped := &family{name: "gondi_recv_frames_dropped_total", kind: "counter", help: "Frames dropped by type."}
+	recvQueue := &family{name: "gondi_recv_queue_frames", kind: "gauge", help: "Frames waiting to be captured by type."}
+	recvConnections := &family{name: "gondi_recv_connections", kind: "gauge", help: "Number of connections to the source."}
+	recvProgram := &family{name: "gondi_recv_tally_program", kind: "gauge", help: "Whether the receiver has set the source on program."}
+	recvPreview := &family{name: "gondi_recv_tally_preview", kind: "gauge", help: "Whether the receiver has set the source on preview."}
+
+	for _, source := range sortedKeys(receivers) {
+		recv := receivers[source]
+
+		recvFrames.add(float64(recv.total.VideoFrames), "source", source, "type", "video")
+		recvFrames.add(float64(recv.total.AudioFrames), "source", source, "type", "audio")
+		recvFrames.add(float64(recv.total.MetadataFrames), "source", source, "type", "metadata")
+		recvDropped.add(float64(recv.dropped.VideoFrames), "source", source, "type", "video")
+		recvDropped.add(float64(recv.dropped.AudioFrames), "source", source, "type", "audio")
+		recvDropped.add(float64(recv.dropped.MetadataFrames), "source", source, "type", "metadata")
+		recvQueue.add(float64(recv.queue.VideoFrames), "source", source, "type", "video")
+		recvQueue.add(float64(recv.queue.AudioFrames), "source", source, "type", "audio")
+		recvQueue.add(float64(recv.queue.MetadataFrames), "source", source, "type", "metadata")
+		recvConnections.add(float64(recv.connections), "source", source)
+		recvProgram.add(boolValue(recv.tally.Program), "source", source)
+		recvPreview.add(boolValue(recv.tally.Preview), "source", source)
+	}
+
+	sendFrames := &family{name: "gondi_send_frames_total", kind: "counter", help: "Frames sent by type."}
+	sendConnections := &family{name: "gondi_send_connections", kind: "gauge", help: "Number of receivers connected to the source."}
+	sendProgram := &family{name: "gondi_send_tally_program", kind: "gauge", help: "Whether any receiver has the source on program."}
+	sendPreview := &family{name: "gondi_send_tally_preview", kind: "gauge", help: "Whether any receiver has the source on preview."}
+
+	for _, source := range sortedKeys(senders) {
+		send := senders[source]
+
+		sendFrames.add(float64(send.sent.VideoFrames), "source", source, "type", "video")
+		sendFrames.add(float64(send.sent.AudioFrames), "source", source, "type", "audio")
+		sendFrames.add(float64(send.sent.MetadataFrames), "source", source, "type", "metadata")
+		sendConnections.add(float64(send.connections), "source", source)
+		sendProgram.add(boolValue(send.tally.Program), "source", source)
+		sendPreview.add(boolValue(send.tally.Preview), "source", source)
+	}
+
+	return []*family{
+		recvFrames, recvDropped, recvQueue, recvConnections, recvProgram, recvPreview,
+		sendFrames, sendConnections, sendProgram, sendPreview,
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Write the current metrics in the Prometheus text exposition format. Metrics without samples are left out.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	return writeFamilies(w, c.collect())
+}
+
+func writeFamilies(w io.Writer, families []*family) (int64, error) {
+	cw := &countingWriter{w: w}
+	b := bufio.NewWriter(cw)
+
+	for _, f := range families {
+		if len(f.samples) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+		for _, s := range f.samples {
+			fmt.Fprintf(b, "%s{%s} %s\n", f.name, s.labels, strconv.FormatFloat(s.value, 'g', -1, 64))
+		}
+	}
+
+	err := b.Flush()
+	return cw.n, err
+}
+
+// Serve the current metrics, implementing http.Handler.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	c.WriteTo(w)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
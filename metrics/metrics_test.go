@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitfocus/gondi"
+)
+
+func TestCollectorInstances(t *testing.T) {
+	// Instances that have not been polled can be added and removed without the NDI library
+	c := NewCollector()
+	recv := &gondi.RecvInstance{}
+
+	removeRecv, err := c.AddReceiver(`CAM "1"`, recv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AddReceiver(`CAM "1"`, &gondi.RecvInstance{}); err == nil {
+		t.Error("a source was added twice")
+	}
+	if _, err := c.AddReceiver("", &gondi.RecvInstance{}); err == nil {
+		t.Error("a receiver without a source name was added")
+	}
+	send := &gondi.SendInstance{}
+	removeSend, err := c.addSender("HOST (Out)", send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.addSender("HOST (Out)", &gondi.SendInstance{}); err == nil {
+		t.Error("a sender was added twice")
+	}
+
+	// Removing an instance does not remove another one added with the same name afterwards
+	removeRecv()
+	again := &gondi.RecvInstance{}
+	if _, err := c.AddReceiver(`CAM "1"`, again); err != nil {
+		t.Fatalf("the source could not be added again: %v", err)
+	}
+	removeRecv()
+	removeSend()
+	if c.receivers[`CAM "1"`] != again || len(c.senders) != 0 {
+		t.Errorf("instances left after removing: %v, %v", c.receivers, c.senders)
+	}
+}
+
+func TestCollectorOutput(t *testing.T) {
+	receivers := map[string]receiverStats{
+		`CAM "1"`: {
+			total:       gondi.RecvPerformance{VideoFrames: 250, AudioFrames: 500, MetadataFrames: 3},
+			dropped:     gondi.RecvPerformance{VideoFrames: 2},
+			queue:       gondi.RecvQueue{VideoFrames: 1},
+			connections: 1,
+			tally:       gondi.Tally{Program: true},
+		},
+	}
+	senders := map[string]senderStats{
+		"HOST (Out)": {sent: gondi.SendPerformance{VideoFrames: 100}, connections: 2, tally: gondi.Tally{Preview: true}},
+	}
+
+	var b strings.Builder
+	n, err := writeFamilies(&b, families(receivers, senders))
+	if err != nil || n != int64(b.Len()) {
+		t.Fatalf("wrote %d bytes of %d, %v", n, b.Len(), err)
+	}
+	out := b.String()
+	for _, line := range []string{
+		"# TYPE gondi_recv_frames_total counter",
+		`gondi_recv_frames_total{source="CAM \"1\"",type="video"} 250`,
+		`gondi_recv_frames_dropped_total{source="CAM \"1\"",type="video"} 2`,
+		`gondi_recv_queue_frames{source="CAM \"1\"",type="video"} 1`,
+		`gondi_recv_connections{source="CAM \"1\""} 1`,
+		`gondi_recv_tally_program{source="CAM \"1\""} 1`,
+		`gondi_recv_tally_preview{source="CAM \"1\""} 0`,
+		`gondi_send_frames_total{source="HOST (Out)",type="video"} 100`,
+		`gondi_send_connections{source="HOST (Out)"} 2`,
+		`gondi_send_tally_preview{source="HOST (Out)"} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, out)
+		}
+	}
+
+	// Metrics without samples are left out
+	b.Reset()
+	writeFamilies(&b, families(nil, senders))
+	if strings.Contains(b.String(), "gondi_recv_") {
+		t.Errorf("receiver metrics written without receivers:\n%s", b.String())
+	}
+}
+
+func TestCollectorServeHTTP(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewCollector().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if got := rec.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("content type is %q", got)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("an empty collector wrote:\n%s", rec.Body.String())
+	}
+}
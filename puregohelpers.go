@@ -1,6 +1,10 @@
 package gondi
 
-import "unsafe"
+import (
+	"runtime"
+	"sync"
+	"unsafe"
+)
 
 func goString(c uintptr) string {
 	// We take the address and then dereference it to trick go vet from creating a possible misuse of unsafe.Pointer
@@ -22,6 +26,9 @@ func hasSuffix(s, suffix string) bool {
 	return len(s) >= len(suffix) && s[len(s)-len(suffix):] == suffix
 }
 
+// Get a NUL terminated copy of a string in Go memory. The result is only kept alive by references from Go, and is
+// stored in structs such as Source and MetadataFrame, which pin it for the duration of each SDK call that reads it.
+// Memory that the SDK keeps using after a call returns must come from a cAllocator instead.
 func cString(name string) *byte {
 	if hasSuffix(name, "\x00") {
 		return &(*(*[]byte)(unsafe.Pointer(&name)))[0]
@@ -30,3 +37,94 @@ func cString(name string) *byte {
 	copy(b, name)
 	return &b[0]
 }
+
+// cAllocator owns memory that is handed to the NDI library for the lifetime of an instance, such as the strings
+// and structs of the create settings. Everything it hands out is pinned, so it stays valid and at the same
+// address until free is called. Each instance owns one allocator, and frees it in Destroy after the SDK
+// instance is gone.
+type cAllocator struct {
+	mu     sync.Mutex
+	pinner runtime.Pinner
+	bufs   [][]byte
+	freed  bool
+}
+
+// Get a pinned, NUL terminated copy of a string.
+func (a *cAllocator) cString(s string) *byte {
+	b := make([]byte, len(s)+1)
+	copy(b, s)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.freed {
+		panic("gondi: C string allocated after the owning instance was destroyed")
+	}
+	a.pinner.Pin(&b[0])
+	a.bufs = append(a.bufs, b)
+
+	return &b[0]
+}
+
+// Like cString, but an empty string returns nil, which the SDK treats as "use the default".
+func (a *cAllocator) cStringOrNil(s string) *byte {
+	if s == "" {
+		return nil
+	}
+	return a.cString(s)
+}
+
+// Pin a Go allocated struct, such as create settings, until free is called.
+func (a *cAllocator) pin(ptr any) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.freed {
+		panic("gondi: memory pinned after the owning instance was destroyed")
+	}
+	a.pinner.Pin(ptr)
+}
+
+// Get the number of strings currently allocated.
+func (a *cAllocator) allocated() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return len(a.bufs)
+}
+
+// Unpin and release everything. Calling free more than once is safe.
+func (a *cAllocator) free() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.pinner.Unpin()
+	a.bufs = nil
+	a.freed = true
+}
+
+// Pin a source and its strings until the pinner is unpinned. The SDK reads the strings through the pointers in the
+// struct, so they must stay in place for the duration of the call. A nil source is ignored.
+func (s *Source) pin(pinner *runtime.Pinner) {
+	if s == nil {
+		return
+	}
+	pinner.Pin(s)
+	if s.name != nil {
+		pinner.Pin(s.name)
+	}
+	if s.address != nil {
+		pinner.Pin(s.address)
+	}
+}
+
+// Pin a metadata frame and its data until the pinner is unpinned. A nil frame is ignored.
+func (p *MetadataFrame) pin(pinner *runtime.Pinner) {
+	if p == nil {
+		return
+	}
+	pinner.Pin(p)
+	if p.Data != nil {
+		pinner.Pin(p.Data)
+	}
+}
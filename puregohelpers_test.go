@@ -0,0 +1,144 @@
+package gondi
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// Allocate garbage and run the garbage collector, to give it every chance to reuse memory that is not kept alive.
+func gcPressure() {
+	for i := 0; i < 100; i++ {
+		_ = make([]byte, 64*1024)
+	}
+	runtime.GC()
+}
+
+func TestAllocatorUnderGC(t *testing.T) {
+	var a cAllocator
+	settings := &sendCreateSettings{}
+	a.pin(settings)
+
+	const goroutines, perGoroutine = 8, 500
+	ptrs := make([][]*byte, goroutines)
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				ptrs[g] = append(ptrs[g], a.cString(fmt.Sprintf("source %d-%d with a name long enough to not be tiny", g, i)))
+				if i%100 == 0 {
+					gcPressure()
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	gcPressure()
+
+	if n := a.allocated(); n != goroutines*perGoroutine {
+		t.Errorf("allocated %d strings, want %d", n, goroutines*perGoroutine)
+	}
+	for g := range ptrs {
+		for i, ptr := range ptrs[g] {
+			want := fmt.Sprintf("source %d-%d with a name long enough to not be tiny", g, i)
+			if got := goString(uintptr(unsafe.Pointer(ptr))); got != want {
+				t.Fatalf("string %d-%d is %q, want %q", g, i, got, want)
+			}
+		}
+	}
+
+	if a.cStringOrNil("") != nil {
+		t.Error("cStringOrNil did not return nil for an empty string")
+	}
+
+	a.free()
+	a.free()
+	if n := a.allocated(); n != 0 {
+		t.Errorf("%d strings still allocated after free", n)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("allocating after free did not panic")
+		}
+	}()
+	a.cString("after free")
+}
+
+func TestAllocatorReleasesMemory(t *testing.T) {
+	var a cAllocator
+	var finalized atomic.Int32
+
+	const count = 100
+	for i := 0; i < count; i++ {
+		ptr := a.cString(fmt.Sprintf("string %03d that is long enough to get its own allocation", i))
+		runtime.SetFinalizer(ptr, func(*byte) { finalized.Add(1) })
+	}
+
+	gcPressure()
+	if n := finalized.Load(); n != 0 {
+		t.Fatalf("%d strings were collected while still allocated", n)
+	}
+
+	a.free()
+	deadline := time.Now().Add(5 * time.Second)
+	for finalized.Load() < count && time.Now().Before(deadline) {
+		gcPressure()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := finalized.Load(); n != count {
+		t.Errorf("%d of %d strings were collected after free", n, count)
+	}
+}
+
+func TestMetadataFrameUnderGC(t *testing.T) {
+	frames := make([]*MetadataFrame, 1000)
+	for i := range frames {
+		frames[i] = NewMetadataFrame(fmt.Sprintf(`<data index="%d" text="The quick brown fox jumps over the lazy dog"/>`, i))
+		if i%100 == 0 {
+			gcPressure()
+		}
+	}
+	gcPressure()
+
+	for i, frame := range frames {
+		want := fmt.Sprintf(`<data index="%d" text="The quick brown fox jumps over the lazy dog"/>`, i)
+		if got := frame.GetData(); got != want {
+			t.Fatalf("frame %d contains %q, want %q", i, got, want)
+		}
+	}
+
+	var source Source
+	source.Set("MACHINE (Output 1)", "")
+	gcPressure()
+	if source.Name() != "MACHINE (Output 1)" || source.address != nil {
+		t.Errorf("source is %q at %q", source.Name(), source.Address())
+	}
+}
+
+func TestPinForCall(t *testing.T) {
+	// Strings may point into Go heap memory, or into a literal when they are already NUL terminated
+	sources := []*Source{NewSource("MACHINE (Output 1)", "10.0.0.1:5961"), NewSource("MACHINE (Output 2)\x00", ""), nil}
+	frames := []*MetadataFrame{NewMetadataFrame(`<data/>`), NewMetadataFrame("<data/>\x00"), {}, nil}
+
+	var pinner runtime.Pinner
+	for _, source := range sources {
+		source.pin(&pinner)
+	}
+	for _, frame := range frames {
+		frame.pin(&pinner)
+	}
+	gcPressure()
+	pinner.Unpin()
+
+	if sources[0].Name() != "MACHINE (Output 1)" || frames[0].GetData() != "<data/>" {
+		t.Errorf("pinned data changed: %q, %q", sources[0].Name(), frames[0].GetData())
+	}
+}
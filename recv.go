@@ -2,114 +2,361 @@ package gondi
 
 import (
 	"errors"
+	"runtime"
 	"unsafe"
+
+	"github.com/bitfocus/gondi/metadata"
 )
 
 // Allocate a new Receiver, using a NewRecvInstanceSetting struct as parameters
 func NewRecvInstance(settings *NewRecvInstanceSettings) (*RecvInstance, error) {
 	assertLibrary()
 
-	var name *byte
-	if settings.Name == "" {
-		name = nil
-	} else {
-		name = cString(settings.Name)
-	}
+	inst := &RecvInstance{}
 
 	intSettings := &recvCreateSettings{
-		sourceToConnectTo: *settings.SourceToConnectTo,
-		colorFormat:       settings.ColorFormat,
-		bandwidth:         settings.Bandwidth,
-		allowVideoFields:  settings.AllowVideoFields,
-		name:              name,
-	}
-
-	inst := &RecvInstance{
-		ndiInstance:    0,
-		createSettings: intSettings,
+		sourceToConnectTo: Source{
+			name:    inst.strings.cStringOrNil(settings.SourceToConnectTo.Name()),
+			address: inst.strings.cStringOrNil(settings.SourceToConnectTo.Address()),
+		},
+		colorFormat:      settings.ColorFormat,
+		bandwidth:        settings.Bandwidth,
+		allowVideoFields: settings.AllowVideoFields,
+		name:             inst.strings.cStringOrNil(settings.Name),
 	}
+	inst.strings.pin(intSettings)
+	inst.createSettings = intSettings
 
 	inst.ndiInstance = ndilib_recv_create_v3(uintptr(unsafe.Pointer(intSettings)))
 	if inst.ndiInstance == 0 {
+		inst.strings.free()
 		return nil, errors.New("unable to create receiver instance")
 	}
 
 	return inst, nil
 }
 
+// Connect to a source, or switch to another source if already connected. Passing nil disconnects the receiver.
+// The SDK copies the source, so it does not need to be kept after this call.
+func (p *RecvInstance) Connect(source *Source) error {
+	assertLibrary()
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
+
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+	source.pin(&pinner)
+
+	ndilib_recv_connect(p.ndiInstance, uintptr(unsafe.Pointer(source)))
+
+	return nil
+}
+
 // This will allow you to receive video, audio and metadata frames from the source you are connected to.
 // Any of the frame pointers can be nil, in which case that type of frame will not be captured.
 // This call can be called on separate threads, so it is possible to have a separate thread for each of video, audio and metadata.
 // This function will return the type of frame that was received, or gondi.FrameTypeNone if no frame was received within the specified timeout.
+// After the instance is closed, gondi.FrameTypeError is returned, the same as when the connection is lost.
+// Use IsClosed to tell them apart.
 func (p *RecvInstance) CaptureV2(vf *VideoFrameV2, af *AudioFrameV2, mf *MetadataFrame, timeoutMs uint32) FrameType {
 	assertLibrary()
+	if !p.state.acquire() {
+		return FrameTypeError
+	}
+	defer p.state.release()
+
+	frameType := FrameType(ndilib_recv_capture_v2(p.ndiInstance, uintptr(unsafe.Pointer(vf)), uintptr(unsafe.Pointer(af)), uintptr(unsafe.Pointer(mf)), timeoutMs))
+	runtime.KeepAlive(vf)
+	runtime.KeepAlive(af)
+	runtime.KeepAlive(mf)
 
-	return FrameType(ndilib_recv_capture_v2(p.ndiInstance, uintptr(unsafe.Pointer(vf)), uintptr(unsafe.Pointer(af)), uintptr(unsafe.Pointer(mf)), timeoutMs))
+	return frameType
 }
 
 // Get the current amount of total and dropped video, audio and metadata frames. This can be used to determine if
 // you have been calling instace.CaptureV2() fast enough to keep up with the incoming stream.
+// After the instance is closed, all values are 0, use IsClosed to tell this apart from no frames.
 func (p *RecvInstance) GetPerformance() (total *RecvPerformance, dropped *RecvPerformance) {
 	assertLibrary()
+	if !p.state.acquire() {
+		return &RecvPerformance{}, &RecvPerformance{}
+	}
+	defer p.state.release()
 	total = &RecvPerformance{}
 	dropped = &RecvPerformance{}
 
 	ndilib_recv_get_performance(p.ndiInstance, uintptr(unsafe.Pointer(total)), uintptr(unsafe.Pointer(dropped)))
+	runtime.KeepAlive(total)
+	runtime.KeepAlive(dropped)
 
 	return total, dropped
 }
 
+// Get the number of video, audio and metadata frames that are waiting to be captured. If this keeps growing,
+// you are not calling CaptureV2 fast enough. After the instance is closed, all values are 0, use IsClosed to tell
+// this apart from an empty queue.
+func (p *RecvInstance) GetQueue() *RecvQueue {
+	assertLibrary()
+	if !p.state.acquire() {
+		return &RecvQueue{}
+	}
+	defer p.state.release()
+	queue := &RecvQueue{}
+
+	ndilib_recv_get_queue(p.ndiInstance, uintptr(unsafe.Pointer(queue)))
+	runtime.KeepAlive(queue)
+
+	return queue
+}
+
 // Set the up-stream tally notifications. This returns FALSE if we are not currently connected to anything. That
 // said, the moment that we do connect to something it will automatically be sent the tally state.
+// After the instance is closed, it always returns false, use IsClosed to tell this apart from not being connected.
 func (p *RecvInstance) SetTally(program bool, preview bool) bool {
 	assertLibrary()
+	if !p.state.acquire() {
+		return false
+	}
+	defer p.state.release()
 	tally := &Tally{program, preview}
 
-	return ndilib_recv_set_tally(p.ndiInstance, uintptr(unsafe.Pointer(tally)))
+	p.tallyMu.Lock()
+	p.tally = *tally
+	p.tallyMu.Unlock()
+
+	ok := ndilib_recv_set_tally(p.ndiInstance, uintptr(unsafe.Pointer(tally)))
+	runtime.KeepAlive(tally)
+
+	return ok
+}
+
+// Get the tally state last set with SetTally. It is what the source is told whenever the receiver is connected.
+func (p *RecvInstance) CurrentTally() Tally {
+	p.tallyMu.Lock()
+	defer p.tallyMu.Unlock()
+
+	return p.tally
 }
 
-// This function will send a meta frame to the source that we are connected too. This returns FALSE if we are
-// not currently connected to anything.
-func (p *RecvInstance) SendMetadata(metadata *MetadataFrame) bool {
+// This function will send a meta frame to the source that we are connected too. This returns ErrNotConnected if we are
+// not currently connected to anything, or ErrClosed if the instance is closed.
+func (p *RecvInstance) SendMetadataFrame(frame *MetadataFrame) error {
 	assertLibrary()
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
+
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+	frame.pin(&pinner)
+
+	p.metadataMu.Lock()
+	ok := ndilib_recv_send_metadata(p.ndiInstance, uintptr(unsafe.Pointer(frame)))
+	p.metadataMu.Unlock()
+
+	if !ok {
+		return ErrNotConnected
+	}
+	return nil
+}
+
+// Encode a value with metadata.Encode, for instance a metadata.PTZZoom, and send it to the source we are connected to.
+// A *MetadataFrame is sent as it is. Like SendMetadataFrame, this returns ErrNotConnected if we are not currently
+// connected to anything.
+func (p *RecvInstance) SendMetadata(v any) error {
+	if frame, ok := v.(*MetadataFrame); ok {
+		return p.SendMetadataFrame(frame)
+	}
+
+	data, err := metadata.Encode(v)
+	if err != nil {
+		return err
+	}
 
-	return ndilib_recv_send_metadata(p.ndiInstance, uintptr(unsafe.Pointer(metadata)))
+	return p.SendMetadataFrame(NewMetadataFrame(data))
+}
+
+// Decode a metadata frame into a type from the metadata package, see metadata.Decode.
+func (p *RecvInstance) DecodeMetadata(frame *MetadataFrame) (any, error) {
+	return metadata.Decode(frame.GetData())
 }
 
 // Add a connection metadata string to the list of what is sent on each new connection. If someone is already connected then
 // this frame will be sent to them immediately.
-func (p *RecvInstance) AddConnectionMetadata(metadata *MetadataFrame) {
+func (p *RecvInstance) AddConnectionMetadata(metadata *MetadataFrame) error {
 	assertLibrary()
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
+
+	p.metadataMu.Lock()
+	defer p.metadataMu.Unlock()
+
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+	metadata.pin(&pinner)
 
 	ndilib_recv_add_connection_metadata(p.ndiInstance, uintptr(unsafe.Pointer(metadata)))
+
+	return nil
 }
 
 // Connection based metadata is data that is sent automatically each time a new connection is received. You queue all of these
 // up and they are sent on each connection. To reset them you need to clear them all and set them up again.
-func (p *RecvInstance) ClearConnectionMetadata() {
+func (p *RecvInstance) ClearConnectionMetadata() error {
+	assertLibrary()
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
+
+	p.metadataMu.Lock()
+	defer p.metadataMu.Unlock()
+
+	ndilib_recv_clear_connection_metadata(p.ndiInstance)
+
+	return nil
+}
+
+// Replace the connection metadata with a new list of frames. Metadata sends and other connection metadata changes
+// wait until the complete list has been added.
+func (p *RecvInstance) replaceConnectionMetadata(frames []*MetadataFrame) error {
 	assertLibrary()
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
+	p.metadataMu.Lock()
+	defer p.metadataMu.Unlock()
+
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+	for _, frame := range frames {
+		frame.pin(&pinner)
+	}
 
 	ndilib_recv_clear_connection_metadata(p.ndiInstance)
+	for _, frame := range frames {
+		ndilib_recv_add_connection_metadata(p.ndiInstance, uintptr(unsafe.Pointer(frame)))
+	}
+
+	return nil
+}
+
+// Get the number of sources this receiver is connected to, which is 0 or 1. After the instance is closed, 0 is returned,
+// use IsClosed to tell this apart from not being connected.
+func (p *RecvInstance) GetNumberOfConnections() int32 {
+	assertLibrary()
+	if !p.state.acquire() {
+		return 0
+	}
+	defer p.state.release()
+
+	return ndilib_recv_get_no_connections(p.ndiInstance)
+}
+
+// Get the URL of the web control page of the connected source, or an empty string if it has none. This can change
+// while connected, CaptureV2 returns gondi.FrameTypeStatusChange when it does. After the instance is closed, an empty
+// string is returned.
+func (p *RecvInstance) GetWebControl() string {
+	assertLibrary()
+	if !p.state.acquire() {
+		return ""
+	}
+	defer p.state.release()
+
+	ret := ndilib_recv_get_web_control(p.ndiInstance)
+	if ret == 0 {
+		return ""
+	}
+	url := goString(ret)
+	ndilib_recv_free_string(p.ndiInstance, ret)
+
+	return url
+}
+
+// Returns true if the connected source supports PTZ control with the messages in the metadata package. This can
+// change while connected, CaptureV2 returns gondi.FrameTypeStatusChange when it does. After the instance is closed,
+// false is returned.
+func (p *RecvInstance) PTZIsSupported() bool {
+	assertLibrary()
+	if !p.state.acquire() {
+		return false
+	}
+	defer p.state.release()
+
+	return ndilib_recv_ptz_is_supported(p.ndiInstance)
 }
 
 // Free the buffers returned by capture for metadata
-func (p *RecvInstance) FreeMetadata(metadata *MetadataFrame) {
+func (p *RecvInstance) FreeMetadata(metadata *MetadataFrame) error {
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
+
 	ndilib_recv_free_metadata(p.ndiInstance, uintptr(unsafe.Pointer(metadata)))
+	runtime.KeepAlive(metadata)
+
+	return nil
 }
 
 // Free the buffers returned by capture for video
-func (p *RecvInstance) FreeVideoV2(vf *VideoFrameV2) {
+func (p *RecvInstance) FreeVideoV2(vf *VideoFrameV2) error {
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
+
 	ndilib_recv_free_video_v2(p.ndiInstance, uintptr(unsafe.Pointer(vf)))
+	runtime.KeepAlive(vf)
+
+	return nil
 }
 
 // Free the buffers returned by capture for audio
-func (p *RecvInstance) FreeAudioV2(af *AudioFrameV2) {
+func (p *RecvInstance) FreeAudioV2(af *AudioFrameV2) error {
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
+
 	ndilib_recv_free_audio_v2(p.ndiInstance, uintptr(unsafe.Pointer(af)))
+	runtime.KeepAlive(af)
+
+	return nil
 }
 
-// Destroy a receiver instance
+// Destroy a receiver instance. It is safe to call Destroy more than once, and from any goroutine.
+// Captures in progress on other goroutines are allowed to return first.
 func (p *RecvInstance) Destroy() {
+	p.Close()
+}
+
+// Close implements io.Closer, and is the same as Destroy. It always returns nil.
+func (p *RecvInstance) Close() error {
 	assertLibrary()
 
-	ndilib_recv_destroy(p.ndiInstance)
+	p.state.close(func() {
+		ndilib_recv_destroy(p.ndiInstance)
+		p.strings.free()
+	})
+
+	return nil
+}
+
+// Returns true if the instance has been closed or destroyed.
+func (p *RecvInstance) IsClosed() bool {
+	return p.state.isClosed()
+}
+
+// Register a function to run once when the instance is closed or destroyed, for instance to unregister it from
+// monitoring. If the instance is already closed, fn runs right away. The function must not close the instance again.
+// Call the returned function to remove it.
+func (p *RecvInstance) OnClose(fn func()) (remove func()) {
+	return p.state.onClose(fn)
 }
@@ -0,0 +1,63 @@
+package gondi
+
+// A RecvOption changes a setting of a receiver created with NewRecvInstanceWithOptions.
+type RecvOption func(settings *NewRecvInstanceSettings)
+
+// Connect to this source when the receiver is created. Without it, the receiver is created without a source,
+// and can be connected later with Connect.
+func WithRecvSource(source *Source) RecvOption {
+	return func(settings *NewRecvInstanceSettings) {
+		settings.SourceToConnectTo = source
+	}
+}
+
+// Set the preferred color format, the default is gondi.RecvColorFormatUYVYBGRA.
+// The compressed formats are only supported by the NDI Advanced SDK.
+func WithRecvColorFormat(format RecvColorFormat) RecvOption {
+	return func(settings *NewRecvInstanceSettings) {
+		settings.ColorFormat = format
+	}
+}
+
+// Set the bandwidth, the default is gondi.RecvBandwidthHighest.
+func WithRecvBandwidth(bandwidth RecvBandwidth) RecvOption {
+	return func(settings *NewRecvInstanceSettings) {
+		settings.Bandwidth = bandwidth
+	}
+}
+
+// Set whether fielded video is received as fields, the default is true. When false, fields are de-interlaced.
+func WithRecvAllowVideoFields(allow bool) RecvOption {
+	return func(settings *NewRecvInstanceSettings) {
+		settings.AllowVideoFields = allow
+	}
+}
+
+// Set the name of the receiver, as shown to sources. The default is the application name and instance number.
+func WithRecvName(name string) RecvOption {
+	return func(settings *NewRecvInstanceSettings) {
+		settings.Name = name
+	}
+}
+
+// Get receiver settings with the SDK defaults: gondi.RecvColorFormatUYVYBGRA, gondi.RecvBandwidthHighest,
+// fields allowed, and no source.
+func DefaultRecvInstanceSettings() *NewRecvInstanceSettings {
+	return &NewRecvInstanceSettings{
+		ColorFormat:      RecvColorFormatUYVYBGRA,
+		Bandwidth:        RecvBandwidthHighest,
+		AllowVideoFields: true,
+	}
+}
+
+// Allocate a new Receiver starting from the SDK defaults, see DefaultRecvInstanceSettings, with the given options applied.
+//
+//	receiver, err := gondi.NewRecvInstanceWithOptions(gondi.WithRecvSource(source), gondi.WithRecvName("Monitor"))
+func NewRecvInstanceWithOptions(options ...RecvOption) (*RecvInstance, error) {
+	settings := DefaultRecvInstanceSettings()
+	for _, option := range options {
+		option(settings)
+	}
+
+	return NewRecvInstance(settings)
+}
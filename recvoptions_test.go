@@ -0,0 +1,26 @@
+package gondi
+
+import "testing"
+
+func TestRecvOptions(t *testing.T) {
+	settings := DefaultRecvInstanceSettings()
+	if settings.ColorFormat != RecvColorFormatUYVYBGRA || settings.Bandwidth != RecvBandwidthHighest || !settings.AllowVideoFields {
+		t.Errorf("defaults do not match the SDK: %+v", settings)
+	}
+
+	source := NewSource("MACHINE (Camera)", "")
+	for _, option := range []RecvOption{
+		WithRecvSource(source),
+		WithRecvColorFormat(RecvColorFormatBest),
+		WithRecvBandwidth(RecvBandwidthAudioOnly),
+		WithRecvAllowVideoFields(false),
+		WithRecvName("Monitor"),
+	} {
+		option(settings)
+	}
+
+	if settings.SourceToConnectTo.Name() != "MACHINE (Camera)" || settings.ColorFormat != RecvColorFormatBest ||
+		settings.Bandwidth != RecvBandwidthAudioOnly || settings.AllowVideoFields || settings.Name != "Monitor" {
+		t.Errorf("options were not applied: %+v", settings)
+	}
+}
@@ -2,30 +2,31 @@ package gondi
 
 import (
 	"errors"
+	"runtime"
 	"unsafe"
 )
 
+const followPollTimeoutMs = 500
+
 // Setup a routed destination, specified by name and groups.
 // The groups property may be empty, and it will use the default from NDI access manager.
 func NewRoutingInstance(name string, groups string) (*RoutingInstance, error) {
 	assertLibrary()
 
+	instance := &RoutingInstance{name: name, groups: groups}
 	settings := &routingCreateSettings{
-		name:   cString(name),
-		groups: cString(groups),
-	}
-
-	if groups == "" {
-		settings.groups = nil
+		name:   instance.strings.cString(name),
+		groups: instance.strings.cStringOrNil(groups),
 	}
+	instance.strings.pin(settings)
+	instance.createSettings = settings
 
-	inst := ndilib_routing_create(uintptr(unsafe.Pointer(settings)))
-	if inst == 0 {
+	instance.ndiInstance = ndilib_routing_create(uintptr(unsafe.Pointer(settings)))
+	if instance.ndiInstance == 0 {
+		instance.strings.free()
 		return nil, errors.New("unable to create routing instance")
 	}
 
-	instance := &RoutingInstance{inst, settings, name, groups}
-
 	return instance, nil
 }
 
@@ -39,23 +40,224 @@ func (p *RoutingInstance) Groups() string {
 	return p.groups
 }
 
-// Change the source this routing instance is connected to.
-func (p *RoutingInstance) Change(source *Source) {
+// Change the source this routing instance is connected to. An error is returned if the SDK rejects the change.
+func (p *RoutingInstance) Change(source *Source) error {
+	p.routeMu.Lock()
+	defer p.routeMu.Unlock()
+
+	return p.change(source)
+}
+
+// Change the source, with routeMu held.
+func (p *RoutingInstance) change(source *Source) error {
 	assertLibrary()
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
 
-	ndilib_routing_change(p.ndiInstance, uintptr(unsafe.Pointer(source)))
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+	source.pin(&pinner)
+
+	ok := ndilib_routing_change(p.ndiInstance, uintptr(unsafe.Pointer(source)))
+	if !ok {
+		return errors.New("unable to change routing source")
+	}
+
+	p.routed.Set(source.Name(), source.Address())
+
+	return nil
 }
 
 // Clear the current source this routing instance is connected to. Should return black to watchers.
-func (p *RoutingInstance) Clear() {
+// An error is returned if the SDK rejects the change.
+func (p *RoutingInstance) Clear() error {
 	assertLibrary()
+	p.routeMu.Lock()
+	defer p.routeMu.Unlock()
+
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
+
+	if !ndilib_routing_clear(p.ndiInstance) {
+		return errors.New("unable to clear routing source")
+	}
+
+	p.routed.Set("", "")
+
+	return nil
+}
+
+// Get the source currently routed with Change, as a copy owned by Go. Returns false if the routing is cleared.
+func (p *RoutingInstance) Current() (*Source, bool) {
+	p.routeMu.Lock()
+	defer p.routeMu.Unlock()
+
+	if p.routed.name == nil {
+		return nil, false
+	}
+
+	return NewSource(p.routed.Name(), p.routed.Address()), true
+}
+
+// Get the number of receivers connected to the routed output. If you specify a timeout that is not 0, it will wait
+// until there are connections for this amount of time. After the instance is closed, 0 is returned, use IsClosed to
+// tell this apart from no connections.
+func (p *RoutingInstance) GetNumberOfConnections(timeoutMs uint32) int32 {
+	assertLibrary()
+	if !p.state.acquire() {
+		return 0
+	}
+	defer p.state.release()
+
+	return ndilib_routing_get_no_connections(p.ndiInstance, timeoutMs)
+}
+
+// Get the full name of the routed output as receivers see it, including the machine name.
+// The returned source is a copy owned by Go.
+func (p *RoutingInstance) SourceName() (*Source, error) {
+	assertLibrary()
+	if !p.state.acquire() {
+		return nil, ErrClosed
+	}
+	defer p.state.release()
+
+	ret := ndilib_routing_get_source_name(p.ndiInstance)
+
+	// We take the address and then dereference it to trick go vet from creating a possible misuse of unsafe.Pointer
+	source := (*Source)(*(*unsafe.Pointer)(unsafe.Pointer(&ret)))
+	if source == nil {
+		return nil, errors.New("unable to get the source name")
+	}
+
+	return NewSource(source.Name(), source.Address()), nil
+}
 
-	ndilib_routing_clear(p.ndiInstance)
+// Follow the routed source using a finder. When a source with the routed name shows up with a different address, for
+// instance after it restarted on another machine or interface, Change is called again with the new address.
+// Calling Follow again replaces the finder. Following stops when StopFollowing is called, when the finder is closed,
+// or when this instance is closed. The finder is not closed by the routing instance.
+func (p *RoutingInstance) Follow(finder *FindInstance) error {
+	if p.state.isClosed() {
+		return ErrClosed
+	}
+
+	p.routeMu.Lock()
+	defer p.routeMu.Unlock()
+
+	p.follow = finder
+	p.workers.start("follow", p.followSource)
+
+	return nil
+}
+
+// Stop following the routed source. The goroutine following it is stopped too.
+func (p *RoutingInstance) StopFollowing() {
+	p.routeMu.Lock()
+	defer p.routeMu.Unlock()
+
+	p.follow = nil
+	p.workers.stop("follow")
+}
+
+func (p *RoutingInstance) followSource(stop <-chan struct{}) {
+	var last *FindInstance
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		p.routeMu.Lock()
+		finder := p.follow
+		p.routeMu.Unlock()
+
+		if finder == nil {
+			return
+		}
+		if finder.state.isClosed() {
+			p.stopFollowingClosed(finder)
+			return
+		}
+
+		// Check the sources when the finder changes, and whenever the list of sources has changed
+		if !finder.WaitForSources(followPollTimeoutMs) && finder == last {
+			continue
+		}
+		last = finder
+
+		p.refollow(finder, finder.GetCurrentSources())
+	}
+}
+
+// Stop following once the finder has been closed, unless another one has been set to follow meanwhile.
+func (p *RoutingInstance) stopFollowingClosed(finder *FindInstance) {
+	p.routeMu.Lock()
+	defer p.routeMu.Unlock()
+
+	if p.follow == finder {
+		p.follow = nil
+		p.workers.stop("follow")
+	}
 }
 
-// Destroy this routing instance.
+// Change to the new address of the routed source, if it has moved.
+func (p *RoutingInstance) refollow(finder *FindInstance, sources []*Source) {
+	p.routeMu.Lock()
+	defer p.routeMu.Unlock()
+
+	if p.follow != finder {
+		return
+	}
+
+	if source, moved := movedSource(&p.routed, sources); moved {
+		p.change(source)
+	}
+}
+
+// Find the source with the same name as routed, and return it if its address is different.
+func movedSource(routed *Source, sources []*Source) (*Source, bool) {
+	name := routed.Name()
+	if name == "" {
+		return nil, false
+	}
+
+	for _, source := range sources {
+		if source.Name() == name {
+			address := source.Address()
+			return NewSource(name, address), address != "" && address != routed.Address()
+		}
+	}
+
+	return nil, false
+}
+
+// Destroy this routing instance. It is safe to call Destroy more than once, and from any goroutine.
 func (p *RoutingInstance) Destroy() {
+	p.Close()
+}
+
+// Close implements io.Closer, and is the same as Destroy. It always returns nil.
+func (p *RoutingInstance) Close() error {
 	assertLibrary()
 
-	ndilib_routing_destroy(p.ndiInstance)
+	// The follow worker must be stopped before closing, as it takes the state lock
+	p.workers.shutdown()
+
+	p.state.close(func() {
+		ndilib_routing_destroy(p.ndiInstance)
+		p.strings.free()
+	})
+
+	return nil
+}
+
+// Returns true if the instance has been closed or destroyed.
+func (p *RoutingInstance) IsClosed() bool {
+	return p.state.isClosed()
 }
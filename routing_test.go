@@ -0,0 +1,71 @@
+package gondi
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMovedSource(t *testing.T) {
+	routed := NewSource("A (Camera 1)", "10.0.0.1:5961")
+	sources := []*Source{
+		NewSource("A (Camera 2)", "10.0.0.1:5962"),
+		NewSource("A (Camera 1)", "10.0.0.1:5961"),
+	}
+
+	if _, moved := movedSource(routed, sources); moved {
+		t.Error("source at the same address was reported as moved")
+	}
+
+	sources[1] = NewSource("A (Camera 1)", "10.0.0.7:5961")
+	source, moved := movedSource(routed, sources)
+	if !moved || source.Address() != "10.0.0.7:5961" {
+		t.Errorf("moved source was not found, got %v", source)
+	}
+
+	if _, moved := movedSource(routed, sources[:1]); moved {
+		t.Error("missing source was reported as moved")
+	}
+	if _, moved := movedSource(&Source{}, sources); moved {
+		t.Error("cleared routing was reported as moved")
+	}
+}
+
+func TestFollowStops(t *testing.T) {
+	// Stand in for the SDK with a finder without any sources
+	var waits atomic.Int32
+	stubLibrary(t, &ndilib_find_wait_for_sources, func(instance uintptr, timeoutMs uint32) bool {
+		waits.Add(1)
+		time.Sleep(time.Millisecond)
+		return false
+	})
+	stubLibrary(t, &ndilib_find_get_current_sources, func(instance uintptr, numSources uintptr) uintptr { return 0 })
+
+	p := &RoutingInstance{}
+	finder := &FindInstance{}
+	waitStopped := func(when string) {
+		t.Helper()
+		for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+			p.workers.mu.Lock()
+			live := len(p.workers.live)
+			p.workers.mu.Unlock()
+			if live == 0 {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("the follow goroutine is still running %s", when)
+			}
+		}
+	}
+
+	p.Follow(finder)
+	for waits.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	p.StopFollowing()
+	waitStopped("after StopFollowing")
+
+	p.Follow(finder)
+	finder.state.close(func() {})
+	waitStopped("after the finder was closed")
+}
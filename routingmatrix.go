@@ -0,0 +1,231 @@
+package gondi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// A crosspoint of a RoutingMatrix, routing a source to a named output. An empty Source means the output is cleared.
+type Crosspoint struct {
+	Output  string `json:"output"`
+	Source  string `json:"source,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+type routingMatrixOutput struct {
+	output  *RoutingInstance
+	source  string
+	address string
+}
+
+type routingMatrixState struct {
+	Groups  string       `json:"groups,omitempty"`
+	Outputs []Crosspoint `json:"outputs"`
+}
+
+// RoutingMatrix owns a set of named routed outputs, each a RoutingInstance, and keeps track of the source routed to each
+// of them. It is safe to use from multiple goroutines.
+type RoutingMatrix struct {
+	mu      sync.Mutex
+	groups  string
+	order   []string
+	outputs map[string]*routingMatrixOutput
+}
+
+// Create an empty routing matrix. Outputs added to it are created in the given groups, empty means the default groups.
+func NewRoutingMatrix(groups string) *RoutingMatrix {
+	return &RoutingMatrix{
+		groups:  groups,
+		outputs: make(map[string]*routingMatrixOutput),
+	}
+}
+
+// Add a routed output, which shows up as an NDI source with the given name. The output starts out cleared.
+func (m *RoutingMatrix) AddOutput(name string) error {
+	if err := validateSourceName(name); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.outputs[name]; ok {
+		return fmt.Errorf("routing output %q already exists", name)
+	}
+
+	output, err := NewRoutingInstance(name, m.groups)
+	if err != nil {
+		return err
+	}
+
+	m.outputs[name] = &routingMatrixOutput{output: output}
+	m.order = append(m.order, name)
+
+	return nil
+}
+
+// Remove a routed output and destroy its routing instance.
+func (m *RoutingMatrix) RemoveOutput(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	output, ok := m.outputs[name]
+	if !ok {
+		return fmt.Errorf("unknown routing output %q", name)
+	}
+
+	delete(m.outputs, name)
+	for i, n := range m.order {
+		if n == name {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+
+	return output.output.Close()
+}
+
+// Get the names of the outputs, in the order they were added.
+func (m *RoutingMatrix) Outputs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string(nil), m.order...)
+}
+
+// Route a source to an output. A nil source clears the output.
+func (m *RoutingMatrix) Route(output string, source *Source) error {
+	return m.Salvo([]Crosspoint{{Output: output, Source: source.Name(), Address: source.Address()}})
+}
+
+// Route a source to an output by its full name, for instance "MACHINE (Camera 1)". An empty name clears the output.
+func (m *RoutingMatrix) RouteName(output string, sourceName string) error {
+	return m.Salvo([]Crosspoint{{Output: output, Source: sourceName}})
+}
+
+// Clear an output, so it shows black.
+func (m *RoutingMatrix) Clear(output string) error {
+	return m.Salvo([]Crosspoint{{Output: output}})
+}
+
+// Get the source currently routed to an output. The returned source is a copy owned by Go. Returns false if the
+// output is cleared or does not exist.
+func (m *RoutingMatrix) Current(output string) (*Source, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.outputs[output]
+	if !ok || o.source == "" {
+		return nil, false
+	}
+
+	return NewSource(o.source, o.address), true
+}
+
+// Get the current crosspoint of every output, in the order the outputs were added.
+func (m *RoutingMatrix) Crosspoints() []Crosspoint {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.crosspoints()
+}
+
+func (m *RoutingMatrix) crosspoints() []Crosspoint {
+	crosspoints := make([]Crosspoint, 0, len(m.order))
+	for _, name := range m.order {
+		o := m.outputs[name]
+		crosspoints = append(crosspoints, Crosspoint{Output: name, Source: o.source, Address: o.address})
+	}
+
+	return crosspoints
+}
+
+// Apply several crosspoints at once. All outputs are checked to exist before anything is changed, and no other
+// changes are made to the matrix until the whole salvo has been applied. If some changes fail, the others are still
+// applied, and the errors are returned together.
+func (m *RoutingMatrix) Salvo(crosspoints []Crosspoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, crosspoint := range crosspoints {
+		if _, ok := m.outputs[crosspoint.Output]; !ok {
+			return fmt.Errorf("unknown routing output %q", crosspoint.Output)
+		}
+	}
+
+	var errs []error
+	for _, crosspoint := range crosspoints {
+		o := m.outputs[crosspoint.Output]
+
+		var err error
+		if crosspoint.Source == "" {
+			err = o.output.Clear()
+		} else {
+			err = o.output.Change(NewSource(crosspoint.Source, crosspoint.Address))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("routing output %q: %w", crosspoint.Output, err))
+			continue
+		}
+
+		o.source = crosspoint.Source
+		o.address = crosspoint.Address
+		if o.source == "" {
+			o.address = ""
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Get the groups and crosspoints of the matrix as JSON, to be restored later with Restore.
+func (m *RoutingMatrix) MarshalJSON() ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return json.Marshal(routingMatrixState{Groups: m.groups, Outputs: m.crosspoints()})
+}
+
+// Restore crosspoints saved with MarshalJSON. Outputs that do not exist yet are added, and all saved crosspoints
+// are applied as a salvo. Outputs that are not in the saved state are left as they are. The groups of an existing
+// matrix are not changed.
+func (m *RoutingMatrix) Restore(data []byte) error {
+	var state routingMatrixState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	for _, crosspoint := range state.Outputs {
+		m.mu.Lock()
+		_, exists := m.outputs[crosspoint.Output]
+		m.mu.Unlock()
+
+		if !exists {
+			if err := m.AddOutput(crosspoint.Output); err != nil {
+				return err
+			}
+		}
+	}
+
+	return m.Salvo(state.Outputs)
+}
+
+// Destroy all outputs of the matrix. The matrix is empty afterwards, and can be reused.
+func (m *RoutingMatrix) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for _, name := range m.order {
+		if err := m.outputs[name].output.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	m.order = nil
+	m.outputs = make(map[string]*routingMatrixOutput)
+
+	return errors.Join(errs...)
+}
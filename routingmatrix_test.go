@@ -0,0 +1,112 @@
+package gondi
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// The state the SDK keeps for a routing instance.
+type stubRoutingOutput struct {
+	source  string
+	closed  bool
+	failing bool
+}
+
+// Stand in for the SDK with routing instances kept by output name.
+func stubRouting(t *testing.T) map[string]*stubRoutingOutput {
+	outputs := map[string]*stubRoutingOutput{}
+	var names []string
+	output := func(instance uintptr) *stubRoutingOutput { return outputs[names[instance-1]] }
+
+	stubLibrary(t, &ndilib_routing_create, func(settings uintptr) uintptr {
+		name := goString(uintptr(unsafe.Pointer(stubArg[routingCreateSettings](settings).name)))
+		outputs[name] = &stubRoutingOutput{}
+		names = append(names, name)
+		return uintptr(len(names))
+	})
+	stubLibrary(t, &ndilib_routing_change, func(instance uintptr, source uintptr) bool {
+		if output(instance).failing {
+			return false
+		}
+		output(instance).source = stubArg[Source](source).Name()
+		return true
+	})
+	stubLibrary(t, &ndilib_routing_clear, func(instance uintptr) bool {
+		output(instance).source = ""
+		return true
+	})
+	stubLibrary(t, &ndilib_routing_destroy, func(instance uintptr) {
+		output(instance).closed = true
+	})
+
+	return outputs
+}
+
+func TestRoutingMatrix(t *testing.T) {
+	sdk := stubRouting(t)
+	m := NewRoutingMatrix("studio")
+
+	m.AddOutput("Program")
+	m.AddOutput("Preview")
+	if err := m.AddOutput("Program"); err == nil {
+		t.Error("adding an output twice did not fail")
+	}
+
+	if err := m.RouteName("Program", "A (Camera 1)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Route("Preview", NewSource("A (Camera 2)", "10.0.0.2:5961")); err != nil {
+		t.Fatal(err)
+	}
+	if source, ok := m.Current("Preview"); !ok || source.Name() != "A (Camera 2)" || source.Address() != "10.0.0.2:5961" {
+		t.Errorf("unexpected current source for Preview: %v", source)
+	}
+
+	// A salvo with an unknown output changes nothing
+	if err := m.Salvo([]Crosspoint{{Output: "Program"}, {Output: "Aux"}}); err == nil {
+		t.Error("salvo with an unknown output did not fail")
+	}
+	if sdk["Program"].source != "A (Camera 1)" {
+		t.Error("a failed salvo changed the routing")
+	}
+
+	// A failed change keeps the previous source
+	sdk["Preview"].failing = true
+	if err := m.Salvo([]Crosspoint{{Output: "Program", Source: "A (Camera 3)"}, {Output: "Preview", Source: "A (Camera 4)"}}); err == nil {
+		t.Error("salvo did not report the failed change")
+	}
+	sdk["Preview"].failing = false
+	if source, _ := m.Current("Program"); source.Name() != "A (Camera 3)" {
+		t.Errorf("Program has %q after the salvo", source.Name())
+	}
+	if source, _ := m.Current("Preview"); source.Name() != "A (Camera 2)" {
+		t.Errorf("Preview has %q after a failed change", source.Name())
+	}
+
+	data, err := m.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+	if !sdk["Program"].closed || len(m.Outputs()) != 0 {
+		t.Error("close did not destroy the outputs")
+	}
+
+	restored := NewRoutingMatrix("studio")
+	defer restored.Close()
+	if err := restored.Restore(data); err != nil {
+		t.Fatal(err)
+	}
+	outputs := restored.Outputs()
+	if len(outputs) != 2 || outputs[0] != "Program" || outputs[1] != "Preview" {
+		t.Errorf("restored outputs %v", outputs)
+	}
+	if sdk["Program"].source != "A (Camera 3)" || sdk["Preview"].source != "A (Camera 2)" {
+		t.Error("restore did not apply the crosspoints")
+	}
+
+	restored.Clear("Program")
+	if _, ok := restored.Current("Program"); ok {
+		t.Error("Program is still routed after clear")
+	}
+}
@@ -1,29 +1,91 @@
 package gondi
 
 import (
+	"encoding/json"
 	"errors"
+	"runtime"
+	"strings"
+	"unicode/utf8"
 	"unsafe"
+
+	"github.com/bitfocus/gondi/metadata"
 )
 
 // Set up a sender instance using the specified name and string.
 // Syncronous calls will block on either audio or video frames, or both, depending on the clockVideo and clockAudio parameters, to make sure that the frames are sent at the correct time.
 func NewSendInstance(name string, groups string, clockVideo bool, clockAudio bool) (*SendInstance, error) {
+	return newSendInstance(name, groups, clockVideo, clockAudio, "")
+}
+
+// Set up a sender instance using a SendOptions struct. Unlike NewSendInstance, the name is validated, and a configuration
+// JSON for this sender can be specified.
+func NewSendInstanceWithOptions(options SendOptions) (*SendInstance, error) {
+	if err := validateSourceName(options.Name); err != nil {
+		return nil, err
+	}
+	if options.Config != "" && !json.Valid([]byte(options.Config)) {
+		return nil, errors.New("send instance config is not valid JSON")
+	}
+
+	return newSendInstance(options.Name, options.Groups, options.ClockVideo, options.ClockAudio, options.Config)
+}
+
+// Check that a name can be used as an NDI source name.
+func validateSourceName(name string) error {
+	if name == "" {
+		return errors.New("source name is empty")
+	}
+	if strings.ContainsRune(name, 0) {
+		return errors.New("source name contains a NUL character")
+	}
+	if !utf8.ValidString(name) {
+		return errors.New("source name is not valid UTF-8")
+	}
+
+	return nil
+}
+
+func newSendInstance(name string, groups string, clockVideo bool, clockAudio bool, config string) (*SendInstance, error) {
 	assertLibrary()
 
-	settings := &sendCreateSettings{cString(name), cString(groups), clockVideo, clockAudio}
-	instance := ndilib_send_create_v2(uintptr(unsafe.Pointer(settings)))
-	if instance == 0 {
+	inst := &SendInstance{}
+	settings := &sendCreateSettings{inst.strings.cString(name), inst.strings.cStringOrNil(groups), clockVideo, clockAudio}
+	inst.strings.pin(settings)
+	inst.createSettings = settings
+	configData := inst.strings.cStringOrNil(config)
+
+	inst.ndiInstance = ndilib_send_create_v2(uintptr(unsafe.Pointer(settings)), uintptr(unsafe.Pointer(configData)))
+	if inst.ndiInstance == 0 {
+		inst.strings.free()
 		return nil, errors.New("unable to create send instance")
 	}
 
-	return &SendInstance{instance, settings}, nil
+	return inst, nil
 }
 
 // Remember to call Destroy() on the instance when you are done with it. This will free up resources and unregister the sender.
+// It is safe to call Destroy more than once, and from any goroutine. Background watchers are stopped, and calls in progress
+// on other goroutines are allowed to return first. When it is called from a HandleMetadata handler, it does not wait for
+// that handler to return. It always returns nil.
 func (p *SendInstance) Destroy() error {
+	return p.Close()
+}
+
+// Close implements io.Closer, and is the same as Destroy.
+func (p *SendInstance) Close() error {
 	assertLibrary()
 
-	ndilib_send_destroy(p.ndiInstance)
+	// The workers must be stopped before closing, as they take the state lock while polling
+	p.workers.shutdown()
+
+	p.state.close(func() {
+		ndilib_send_destroy(p.ndiInstance)
+		p.strings.free()
+	})
+
+	p.tallyWatchers.close()
+	p.connectionWatchers.close()
+	p.metadata.watchers.close()
 
 	return nil
 }
@@ -69,8 +131,7 @@ func NewVideoFrameV2() *VideoFrameV2 {
 	frame.Xres = 0
 	frame.Yres = 0
 	frame.FourCC = FourCCTypeBGRX
-	frame.FrameRateN = 25
-	frame.FrameRateD = 1
+	frame.SetFrameRate(FrameRate25)
 	frame.PictureAspectRatio = 0
 	frame.FrameFormatType = FrameFormatProgressive
 	frame.Timecode = SendTimecodeSynthesize
@@ -83,10 +144,20 @@ func NewVideoFrameV2() *VideoFrameV2 {
 }
 
 // Send a video frame. This call is syncronous and will block until the frame has been sent if you specified clockVideo=true in NewNDISendInstance().
-func (p *SendInstance) SendVideoFrame(frame *VideoFrameV2) {
+func (p *SendInstance) SendVideoFrame(frame *VideoFrameV2) error {
 	assertLibrary()
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
 
 	ndilib_send_send_video_v2(p.ndiInstance, uintptr(unsafe.Pointer(frame)))
+	runtime.KeepAlive(frame)
+	if frame != nil {
+		p.sentVideo.Add(1)
+	}
+
+	return nil
 }
 
 // Send video asynchronously, this call will return immediately, and you need to keep the video frame memory resident until a
@@ -96,75 +167,259 @@ func (p *SendInstance) SendVideoFrame(frame *VideoFrameV2) {
 // - A call to frame.SendVideoFrameAsync() with a different video frame
 // - A call to frame.SendVideoFrame(nil)
 // - A call to frame.Destroy()
-func (p *SendInstance) SendVideoFrameAsync(frame *VideoFrameV2) {
+func (p *SendInstance) SendVideoFrameAsync(frame *VideoFrameV2) error {
 	assertLibrary()
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
 
 	ndilib_send_send_video_async_v2(p.ndiInstance, uintptr(unsafe.Pointer(frame)))
+	runtime.KeepAlive(frame)
+	if frame != nil {
+		p.sentVideo.Add(1)
+	}
+
+	return nil
 }
 
 // Send a metadata frame
-func (p *SendInstance) SendMetadataFrame(frame *MetadataFrame) {
+func (p *SendInstance) SendMetadataFrame(frame *MetadataFrame) error {
 	assertLibrary()
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
+
+	p.metadataMu.Lock()
+	defer p.metadataMu.Unlock()
+
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+	frame.pin(&pinner)
 
 	ndilib_send_send_metadata(p.ndiInstance, uintptr(unsafe.Pointer(frame)))
+	p.sentMetadata.Add(1)
+
+	return nil
+}
+
+// Encode a value with metadata.Encode, for instance a metadata.TallyEcho, and send it to all connected receivers.
+// A *MetadataFrame is sent as it is.
+func (p *SendInstance) SendMetadata(v any) error {
+	if frame, ok := v.(*MetadataFrame); ok {
+		return p.SendMetadataFrame(frame)
+	}
+
+	data, err := metadata.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	return p.SendMetadataFrame(NewMetadataFrame(data))
+}
+
+// Decode a metadata frame into a type from the metadata package, see metadata.Decode.
+func (p *SendInstance) DecodeMetadata(frame *MetadataFrame) (any, error) {
+	return metadata.Decode(frame.GetData())
 }
 
 // This method lets you receive metadata from the other end of the connection.
 // Remember that there might be multiple connections to your sender instance.
+// Received frames must be freed with FreeMetadata. WatchMetadata and HandleMetadata offer a managed alternative.
+// After the instance is closed, gondi.FrameTypeError is returned, use IsClosed to tell this apart from an SDK error.
 func (p *SendInstance) Capture(metadata *MetadataFrame, timeoutMs uint32) FrameType {
 	assertLibrary()
+	if !p.state.acquire() {
+		return FrameTypeError
+	}
+	defer p.state.release()
+
+	frameType := FrameType(ndilib_send_capture(p.ndiInstance, uintptr(unsafe.Pointer(metadata)), timeoutMs))
+	runtime.KeepAlive(metadata)
+
+	return frameType
+}
+
+// Free the buffers returned by Capture for metadata
+func (p *SendInstance) FreeMetadata(metadata *MetadataFrame) error {
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
+
+	ndilib_send_free_metadata(p.ndiInstance, uintptr(unsafe.Pointer(metadata)))
+	runtime.KeepAlive(metadata)
 
-	return FrameType(ndilib_send_capture(p.ndiInstance, uintptr(unsafe.Pointer(metadata)), timeoutMs))
+	return nil
 }
 
 // Add a connection metadata string to the list of what is sent on each new connection. If someone is already connected then
 // this string will be sent to them immediately.
-func (p *SendInstance) AddConnectionMetadata(metadata *MetadataFrame) {
+func (p *SendInstance) AddConnectionMetadata(metadata *MetadataFrame) error {
 	assertLibrary()
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
+
+	p.metadataMu.Lock()
+	defer p.metadataMu.Unlock()
+
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+	metadata.pin(&pinner)
 
 	ndilib_send_add_connection_metadata(p.ndiInstance, uintptr(unsafe.Pointer(metadata)))
+
+	return nil
 }
 
 // Connection based metadata is data that is sent automatically each time a new connection is received. You queue all of these
 // up and they are sent on each connection. To reset them you need to clear them all and set them up again.
-func (p *SendInstance) ClearConnectionMetadata() {
+func (p *SendInstance) ClearConnectionMetadata() error {
 	assertLibrary()
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
+
+	p.metadataMu.Lock()
+	defer p.metadataMu.Unlock()
 
 	ndilib_send_clear_connection_metadata(p.ndiInstance)
+
+	return nil
+}
+
+// Replace the connection metadata with a new list of frames. Metadata sends and other connection metadata changes
+// wait until the complete list has been added.
+func (p *SendInstance) replaceConnectionMetadata(frames []*MetadataFrame) error {
+	assertLibrary()
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
+	p.metadataMu.Lock()
+	defer p.metadataMu.Unlock()
+
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+	for _, frame := range frames {
+		frame.pin(&pinner)
+	}
+
+	ndilib_send_clear_connection_metadata(p.ndiInstance)
+	for _, frame := range frames {
+		ndilib_send_add_connection_metadata(p.ndiInstance, uintptr(unsafe.Pointer(frame)))
+	}
+
+	return nil
 }
 
 // Get the current number of receivers connected to this source. This can be used to avoid even rendering when nothing is connected to the video source.
 // which can significantly improve the efficiency if you want to make a lot of sources available on the network. If you specify a timeout that is not
-// 0 then it will wait until there are connections for this amount of time.
+// 0 then it will wait until there are connections for this amount of time. After the instance is closed, 0 is returned,
+// use IsClosed to tell this apart from no connections.
 func (p *SendInstance) GetNumberOfConnections(timeoutMs uint32) int32 {
 	assertLibrary()
+	if !p.state.acquire() {
+		return 0
+	}
+	defer p.state.release()
 
 	return ndilib_send_get_no_connections(p.ndiInstance, timeoutMs)
 }
 
 // Determine the current tally sate. If you specify a timeout then it will wait until it has changed, otherwise it will simply poll it
 // and return the current tally immediately. The boolean return value is whether anything has actually changed (true) or whether it timed out (false)
+// After the instance is closed, an empty tally and false are returned, use IsClosed to tell this apart from a timeout.
 func (p *SendInstance) GetTally(timeoutMs uint32) (*Tally, bool) {
 	assertLibrary()
+	if !p.state.acquire() {
+		return &Tally{}, false
+	}
+	defer p.state.release()
 	tally := &Tally{}
 
 	changed := ndilib_send_get_tally(p.ndiInstance, uintptr(unsafe.Pointer(tally)), timeoutMs)
+	runtime.KeepAlive(tally)
 
 	return tally, changed
 }
 
 // Send an audio frame. This call is syncronous and will block until the frame has been sent, if you specified clockAudio=true in NewNDISendInstance().
-func (p *SendInstance) SendAudioFrame(frame *AudioFrameV2) {
+func (p *SendInstance) SendAudioFrame(frame *AudioFrameV2) error {
 	assertLibrary()
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
 
 	ndilib_send_send_audio_v2(p.ndiInstance, uintptr(unsafe.Pointer(frame)))
+	runtime.KeepAlive(frame)
+	p.sentAudio.Add(1)
+
+	return nil
 }
 
 // This will assign a new fail-over source for this video source. What this means is that if this video source was to fail
 // any receivers would automatically switch over to use this source, unless this source then came back online. You can specify
 // nil to clear the source.
-func (p *SendInstance) SetFailover(source *Source) {
+func (p *SendInstance) SetFailover(source *Source) error {
 	assertLibrary()
+	if !p.state.acquire() {
+		return ErrClosed
+	}
+	defer p.state.release()
+
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+	source.pin(&pinner)
 
 	ndilib_send_set_failover(p.ndiInstance, uintptr(unsafe.Pointer(source)))
+
+	return nil
+}
+
+// Get the full name of this source as receivers see it, including the machine name, for instance "MACHINE (name)".
+// The returned source is a copy owned by Go.
+func (p *SendInstance) SourceName() (*Source, error) {
+	assertLibrary()
+	if !p.state.acquire() {
+		return nil, ErrClosed
+	}
+	defer p.state.release()
+
+	ret := ndilib_send_get_source_name(p.ndiInstance)
+
+	// We take the address and then dereference it to trick go vet from creating a possible misuse of unsafe.Pointer
+	source := (*Source)(*(*unsafe.Pointer)(unsafe.Pointer(&ret)))
+	if source == nil {
+		return nil, errors.New("unable to get the source name")
+	}
+
+	return NewSource(source.Name(), source.Address()), nil
+}
+
+// Get the number of frames of each type sent since the instance was created. Flushing the asynchronous video
+// frame with SendVideoFrameAsync(nil) is not counted. The counts remain available after the instance is closed.
+func (p *SendInstance) GetPerformance() *SendPerformance {
+	return &SendPerformance{
+		VideoFrames:    p.sentVideo.Load(),
+		AudioFrames:    p.sentAudio.Load(),
+		MetadataFrames: p.sentMetadata.Load(),
+	}
+}
+
+// Returns true if the instance has been closed or destroyed.
+func (p *SendInstance) IsClosed() bool {
+	return p.state.isClosed()
+}
+
+// Register a function to run once when the instance is closed or destroyed, for instance to unregister it from
+// monitoring. If the instance is already closed, fn runs right away. The function must not close the instance again.
+// Call the returned function to remove it.
+func (p *SendInstance) OnClose(fn func()) (remove func()) {
+	return p.state.onClose(fn)
 }
@@ -0,0 +1,23 @@
+package gondi
+
+import "testing"
+
+func TestSendOptionsValidation(t *testing.T) {
+	tests := []SendOptions{
+		{Name: ""},
+		{Name: "camera\x001"},
+		{Name: "camera \xff"},
+		{Name: "camera", Config: "{not json"},
+	}
+
+	// Invalid options are rejected before the library is used
+	for _, options := range tests {
+		if _, err := NewSendInstanceWithOptions(options); err == nil {
+			t.Errorf("options %+v were accepted", options)
+		}
+	}
+
+	if err := validateSourceName("Camera 1 ✓"); err != nil {
+		t.Errorf("valid name was rejected: %v", err)
+	}
+}
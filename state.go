@@ -0,0 +1,112 @@
+package gondi
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrClosed is returned by methods called on an instance after it has been closed or destroyed.
+//
+// Methods that wrap an SDK call without an error result, such as CaptureV2, SetTally or GetNumberOfConnections,
+// keep their signatures, and return the value the SDK uses for "nothing" instead, as documented on each of them.
+// That value cannot be told apart from for instance not being connected, so use IsClosed on the instance
+// when the difference matters.
+var ErrClosed = errors.New("gondi: instance is closed")
+
+// ErrNotConnected is returned when sending to the other end of a connection while nothing is connected.
+var ErrNotConnected = errors.New("gondi: not connected")
+
+var (
+	_ io.Closer = (*SendInstance)(nil)
+	_ io.Closer = (*RecvInstance)(nil)
+	_ io.Closer = (*FindInstance)(nil)
+	_ io.Closer = (*RoutingInstance)(nil)
+)
+
+// Tracks whether the SDK handle of an instance is still valid. Calls into the SDK hold a read lock for their
+// duration, so closing the instance waits for calls in flight, such as a blocking capture, to return first.
+// Calls holding the read lock must not call other methods of the same instance that take it again.
+type instanceState struct {
+	mu     sync.RWMutex
+	closed bool
+	once   sync.Once
+
+	callbacksMu  sync.Mutex
+	callbacks    map[*func()]struct{}
+	callbacksRun bool
+}
+
+// Take the read lock, returns false without holding it if the instance is closed.
+func (s *instanceState) acquire() bool {
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return false
+	}
+	return true
+}
+
+func (s *instanceState) release() {
+	s.mu.RUnlock()
+}
+
+// Returns true if the instance has been closed.
+func (s *instanceState) isClosed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.closed
+}
+
+// Mark the instance as closed, and run destroy once no SDK calls are in flight. Only the first call has any effect,
+// concurrent calls wait for the first one to finish. The callbacks registered with onClose are run by the first call,
+// after destroy, and without any locks held.
+func (s *instanceState) close(destroy func()) {
+	first := false
+	s.once.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.closed = true
+		destroy()
+		first = true
+	})
+	if !first {
+		return
+	}
+
+	s.callbacksMu.Lock()
+	callbacks := s.callbacks
+	s.callbacks = nil
+	s.callbacksRun = true
+	s.callbacksMu.Unlock()
+
+	for fn := range callbacks {
+		(*fn)()
+	}
+}
+
+// Register fn to run when the instance is closed. If it is already closed, fn runs right away.
+// Call the returned function to remove it again.
+func (s *instanceState) onClose(fn func()) (remove func()) {
+	s.callbacksMu.Lock()
+	if s.callbacksRun {
+		s.callbacksMu.Unlock()
+		fn()
+		return func() {}
+	}
+	if s.callbacks == nil {
+		s.callbacks = make(map[*func()]struct{})
+	}
+	key := &fn
+	s.callbacks[key] = struct{}{}
+	s.callbacksMu.Unlock()
+
+	return func() {
+		s.callbacksMu.Lock()
+		defer s.callbacksMu.Unlock()
+
+		delete(s.callbacks, key)
+	}
+}
@@ -0,0 +1,68 @@
+package gondi
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInstanceStateClose(t *testing.T) {
+	var s instanceState
+	destroyed := 0
+
+	// A capture in progress holds the state until it returns
+	if !s.acquire() {
+		t.Fatal("acquire failed on an open instance")
+	}
+	captureDone := false
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		captureDone = true
+		s.release()
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.close(func() {
+				if !captureDone {
+					t.Error("destroy ran while a capture was in progress")
+				}
+				destroyed++
+			})
+		}()
+	}
+	wg.Wait()
+
+	if destroyed != 1 {
+		t.Errorf("destroy ran %d times, want 1", destroyed)
+	}
+	if !s.isClosed() {
+		t.Error("state is not closed")
+	}
+	if s.acquire() {
+		t.Error("acquire succeeded on a closed instance")
+	}
+}
+
+func TestInstanceStateOnClose(t *testing.T) {
+	var s instanceState
+	calls := map[string]int{}
+	s.onClose(func() { calls["kept"]++ })
+	remove := s.onClose(func() { calls["removed"]++ })
+	remove()
+
+	s.close(func() {})
+	s.close(func() {})
+	if calls["kept"] != 1 || calls["removed"] != 0 {
+		t.Errorf("callbacks ran %v times", calls)
+	}
+
+	// Registering after close runs the callback right away
+	s.onClose(func() { calls["late"]++ })
+	if calls["late"] != 1 {
+		t.Error("callback registered after close did not run")
+	}
+}
@@ -0,0 +1,90 @@
+package gondi
+
+import (
+	"context"
+	"runtime"
+	"time"
+	"unsafe"
+)
+
+// How long a single tally poll waits for a change, this bounds how long Destroy waits for the watcher.
+const tallyPollTimeoutMs = 100
+
+// A tally state reported by WatchTally, with the time the change was seen.
+type TallyChange struct {
+	Tally
+	Time time.Time
+}
+
+// Watch the tally state of this sender. The returned channel first receives the current tally, and then every
+// change to it. Repeated reports of the same state are not delivered. The channel is closed when the context is
+// done or the instance is destroyed.
+//
+// All watchers share a single goroutine polling the SDK, which is safe to run alongside sending from other goroutines.
+// A watcher that does not keep up with the changes misses intermediate states, but the last state it receives is
+// always the current one, and CurrentTally is always up to date.
+func (p *SendInstance) WatchTally(ctx context.Context) <-chan TallyChange {
+	assertLibrary()
+
+	p.workers.start("tally", p.pollTally)
+
+	p.tallyMu.Lock()
+	defer p.tallyMu.Unlock()
+
+	if p.tally.Time.IsZero() {
+		tally, _ := p.GetTally(0)
+		p.setTally(*tally)
+	}
+
+	return p.tallyWatchers.add(ctx, 16, &p.tally)
+}
+
+// Get the current tally state. While WatchTally is in use, this is the cached state from the watcher, otherwise
+// the SDK is polled without waiting.
+func (p *SendInstance) CurrentTally() Tally {
+	p.tallyMu.Lock()
+	defer p.tallyMu.Unlock()
+
+	if !p.workers.isRunning("tally") {
+		tally, _ := p.GetTally(0)
+		p.setTally(*tally)
+	}
+
+	return p.tally.Tally
+}
+
+// Store a polled tally state, and return true if it is different from the last one. Called with tallyMu held.
+func (p *SendInstance) setTally(tally Tally) bool {
+	if !p.tally.Time.IsZero() && p.tally.Tally == tally {
+		return false
+	}
+	p.tally = TallyChange{tally, time.Now()}
+
+	return true
+}
+
+func (p *SendInstance) pollTally(stop <-chan struct{}) {
+	// Allocated on the heap, as the SDK writes to it
+	tally := new(Tally)
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if !p.state.acquire() {
+			return
+		}
+		ndilib_send_get_tally(p.ndiInstance, uintptr(unsafe.Pointer(tally)), tallyPollTimeoutMs)
+		runtime.KeepAlive(tally)
+		p.state.release()
+
+		p.tallyMu.Lock()
+		if p.setTally(*tally) {
+			p.tallyWatchers.publish(p.tally)
+		}
+		p.tallyMu.Unlock()
+	}
+}
@@ -0,0 +1,227 @@
+package gondi
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timecodes and timestamps in NDI frames are in 100ns intervals. When they are treated as a time of day,
+// they are relative to the Unix epoch in UTC.
+type Timecode int64
+
+const (
+	// Let the SDK fill in the timecode when the frame is sent.
+	TimecodeSynthesize Timecode = Timecode(SendTimecodeSynthesize)
+
+	// Value of a received timestamp when it was not provided by the sender.
+	RecvTimestampUndefined int64 = math.MaxInt64
+
+	// The number of timecode units in one second.
+	TimecodeUnitsPerSecond = 10_000_000
+)
+
+// Convert a duration to a timecode. The duration is truncated to 100ns.
+func TimecodeFromDuration(d time.Duration) Timecode {
+	return Timecode(d / 100)
+}
+
+// Convert a point in time to a timecode, relative to the Unix epoch.
+func TimecodeFromTime(t time.Time) Timecode {
+	return Timecode(t.Unix()*TimecodeUnitsPerSecond + int64(t.Nanosecond()/100))
+}
+
+// Get the current time as a timecode.
+func TimecodeNow() Timecode {
+	return TimecodeFromTime(time.Now())
+}
+
+// Returns true if the timecode has the undefined value. The SDK uses this value for received timestamps
+// that are not available, and for timecodes that should be synthesized when sending.
+func (t Timecode) IsUndefined() bool {
+	return int64(t) == RecvTimestampUndefined
+}
+
+// Get the timecode as a duration.
+func (t Timecode) Duration() time.Duration {
+	return time.Duration(t) * 100
+}
+
+// Get the timecode as a point in time in UTC.
+func (t Timecode) Time() time.Time {
+	return time.Unix(int64(t)/TimecodeUnitsPerSecond, int64(t)%TimecodeUnitsPerSecond*100).UTC()
+}
+
+// Add a duration to the timecode.
+func (t Timecode) Add(d time.Duration) Timecode {
+	return t + TimecodeFromDuration(d)
+}
+
+// Get the duration between this timecode and an earlier one.
+func (t Timecode) Sub(u Timecode) time.Duration {
+	return time.Duration(t-u) * 100
+}
+
+// Add a number of frames at the given frame rate to the timecode.
+func (t Timecode) AddFrames(frames int64, frameRateN int32, frameRateD int32) Timecode {
+	return t + Timecode(framesToUnits(frames, frameRateN, frameRateD))
+}
+
+// Convert a frame count to 100ns units, rounded up so that the result is never before the start of the frame.
+func framesToUnits(frames int64, frameRateN int32, frameRateD int32) int64 {
+	// Split the multiplication to avoid overflowing on frame counts since the Unix epoch
+	n, d := int64(frameRateN), int64(frameRateD)
+	whole, rest := frames/n, frames%n
+
+	units := rest * d * TimecodeUnitsPerSecond
+	fraction := units / n
+	if units%n > 0 {
+		fraction++
+	}
+
+	return whole*d*TimecodeUnitsPerSecond + fraction
+}
+
+// Get the number of whole frames at the given frame rate since timecode 0.
+func (t Timecode) Frames(frameRateN int32, frameRateD int32) int64 {
+	seconds := int64(t) / TimecodeUnitsPerSecond
+	rest := int64(t) % TimecodeUnitsPerSecond
+	n, d := int64(frameRateN), int64(frameRateD)
+
+	return (seconds*n + rest*n/TimecodeUnitsPerSecond) / d
+}
+
+// Get the timecode of the start of a frame number at the given frame rate.
+func TimecodeFromFrames(frames int64, frameRateN int32, frameRateD int32) Timecode {
+	return Timecode(0).AddFrames(frames, frameRateN, frameRateD)
+}
+
+// Returns true if SMPTE timecode at this frame rate uses drop-frame counting, which is the case for 29.97 and 59.94.
+func isDropFrame(frameRateN int32, frameRateD int32) bool {
+	return frameRateD == 1001 && (frameRateN == 30000 || frameRateN == 60000)
+}
+
+// Get the nominal frame count used for SMPTE timecode, for instance 30 for 29.97.
+func nominalFrameRate(frameRateN int32, frameRateD int32) int64 {
+	return (int64(frameRateN) + int64(frameRateD) - 1) / int64(frameRateD)
+}
+
+// Format the timecode as a SMPTE HH:MM:SS:FF time of day at the given frame rate. Drop-frame rates use
+// a semicolon before the frame count. The frames are counted from midnight UTC of the day of the timecode.
+func (t Timecode) FormatSMPTE(frameRateN int32, frameRateD int32) string {
+	if frameRateN <= 0 || frameRateD <= 0 {
+		return "--:--:--:--"
+	}
+
+	// The time of day is taken first, as a day is not a whole number of frames at fractional rates
+	day := int64(86400 * TimecodeUnitsPerSecond)
+	timeOfDay := int64(t) % day
+	if timeOfDay < 0 {
+		timeOfDay += day
+	}
+
+	fps := nominalFrameRate(frameRateN, frameRateD)
+	frames := Timecode(timeOfDay).Frames(frameRateN, frameRateD)
+
+	separator := ":"
+	if isDropFrame(frameRateN, frameRateD) {
+		separator = ";"
+
+		// Skip the first frame numbers of each minute, except every tenth minute
+		drop := fps / 15
+		perMinute := fps*60 - drop
+		perTenMinutes := perMinute*10 + drop
+		tens := frames / perTenMinutes
+		rest := frames % perTenMinutes
+		frames += drop * 9 * tens
+		if rest > drop {
+			frames += drop * ((rest - drop) / perMinute)
+		}
+	}
+
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d",
+		frames/(fps*3600)%24, frames/(fps*60)%60, frames/fps%60, separator, frames%fps)
+}
+
+// Parse a SMPTE HH:MM:SS:FF (or HH:MM:SS;FF) timecode at the given frame rate. The result is the time of day
+// as a timecode relative to midnight, which can be added to the timecode of a date.
+func ParseSMPTE(s string, frameRateN int32, frameRateD int32) (Timecode, error) {
+	if frameRateN <= 0 || frameRateD <= 0 {
+		return 0, errors.New("invalid frame rate")
+	}
+
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ':' || r == ';' || r == '.' })
+	if len(fields) != 4 {
+		return 0, fmt.Errorf("invalid SMPTE timecode %q", s)
+	}
+
+	var v [4]int64
+	for i, field := range fields {
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid SMPTE timecode %q", s)
+		}
+		v[i] = n
+	}
+
+	fps := nominalFrameRate(frameRateN, frameRateD)
+	hours, minutes, seconds, frames := v[0], v[1], v[2], v[3]
+	if hours > 23 || minutes > 59 || seconds > 59 || frames >= fps {
+		return 0, fmt.Errorf("SMPTE timecode %q is out of range", s)
+	}
+
+	total := ((hours*60+minutes)*60+seconds)*fps + frames
+	if isDropFrame(frameRateN, frameRateD) {
+		drop := fps / 15
+		if seconds == 0 && frames < drop && minutes%10 != 0 {
+			return 0, fmt.Errorf("SMPTE timecode %q does not exist in drop-frame", s)
+		}
+		totalMinutes := hours*60 + minutes
+		total -= drop * (totalMinutes - totalMinutes/10)
+	}
+
+	return TimecodeFromFrames(total, frameRateN, frameRateD), nil
+}
+
+// Get the timecode of the video frame.
+func (p *VideoFrameV2) GetTimecode() Timecode {
+	return Timecode(p.Timecode)
+}
+
+// Set the timecode of the video frame.
+func (p *VideoFrameV2) SetTimecode(t Timecode) {
+	p.Timecode = int64(t)
+}
+
+// Get the timestamp of a received video frame. The boolean is false if the sender did not provide one.
+func (p *VideoFrameV2) GetTimestamp() (Timecode, bool) {
+	return Timecode(p.Timestamp), p.Timestamp != RecvTimestampUndefined && p.Timestamp != 0
+}
+
+// Get the timecode of the audio frame.
+func (p *AudioFrameV2) GetTimecode() Timecode {
+	return Timecode(p.Timecode)
+}
+
+// Set the timecode of the audio frame.
+func (p *AudioFrameV2) SetTimecode(t Timecode) {
+	p.Timecode = int64(t)
+}
+
+// Get the timestamp of a received audio frame. The boolean is false if the sender did not provide one.
+func (p *AudioFrameV2) GetTimestamp() (Timecode, bool) {
+	return Timecode(p.Timestamp), p.Timestamp != RecvTimestampUndefined && p.Timestamp != 0
+}
+
+// Get the timecode of the metadata frame.
+func (p *MetadataFrame) GetTimecode() Timecode {
+	return Timecode(p.Timecode)
+}
+
+// Set the timecode of the metadata frame.
+func (p *MetadataFrame) SetTimecode(t Timecode) {
+	p.Timecode = int64(t)
+}
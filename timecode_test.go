@@ -0,0 +1,90 @@
+package gondi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimecodeTime(t *testing.T) {
+	when := time.Date(2023, 5, 17, 12, 34, 56, 789012300, time.UTC)
+
+	tc := TimecodeFromTime(when)
+	if !tc.Time().Equal(when) {
+		t.Errorf("Time() returned %v, want %v", tc.Time(), when)
+	}
+	if tc.Duration() != time.Duration(when.UnixNano()) {
+		t.Errorf("Duration() returned %v, want %v", tc.Duration(), time.Duration(when.UnixNano()))
+	}
+	if d := tc.Add(time.Second).Sub(tc); d != time.Second {
+		t.Errorf("Sub() returned %v, want 1s", d)
+	}
+	if !TimecodeSynthesize.IsUndefined() || tc.IsUndefined() {
+		t.Error("IsUndefined() returned the wrong result")
+	}
+
+	vf := NewVideoFrameV2()
+	if _, ok := vf.GetTimestamp(); ok {
+		t.Error("GetTimestamp() of a new frame reported a timestamp")
+	}
+}
+
+func TestTimecodeSMPTETimeOfDay(t *testing.T) {
+	// Frames are counted from midnight, not from the epoch, so drop-frame timecode follows the wall clock
+	noon := TimecodeFromTime(time.Date(2023, 5, 17, 12, 0, 0, 0, time.UTC))
+	tests := []struct {
+		n, d  int32
+		smpte string
+	}{
+		{25, 1, "12:00:00:00"},
+		{30000, 1001, "12:00:00;01"},
+		{60000, 1001, "12:00:00;02"},
+		{24000, 1001, "11:59:16:20"},
+	}
+	for _, test := range tests {
+		if got := noon.FormatSMPTE(test.n, test.d); got != test.smpte {
+			t.Errorf("noon at %d/%d formatted as %s, want %s", test.n, test.d, got, test.smpte)
+		}
+	}
+}
+
+func TestTimecodeSMPTE(t *testing.T) {
+	tests := []struct {
+		frames int64
+		n, d   int32
+		smpte  string
+	}{
+		{0, 25, 1, "00:00:00:00"},
+		{25*3600 + 24, 25, 1, "01:00:00:24"},
+		{50*86400 + 1, 50, 1, "00:00:00:01"},
+		{1799, 30000, 1001, "00:00:59;29"},
+		{1800, 30000, 1001, "00:01:00;02"},
+		{17982, 30000, 1001, "00:10:00;00"},
+		{107892 - 1, 30000, 1001, "00:59:59;29"},
+		{107892, 30000, 1001, "01:00:00;00"},
+		{3600, 60000, 1001, "00:01:00;04"},
+		{1800, 30, 1, "00:01:00:00"},
+		{86313, 24000, 1001, "00:59:56:09"},
+	}
+
+	for _, test := range tests {
+		tc := TimecodeFromFrames(test.frames, test.n, test.d)
+		if got := tc.FormatSMPTE(test.n, test.d); got != test.smpte {
+			t.Errorf("frame %d at %d/%d formatted as %s, want %s", test.frames, test.n, test.d, got, test.smpte)
+		}
+
+		parsed, err := ParseSMPTE(test.smpte, test.n, test.d)
+		if err != nil {
+			t.Errorf("ParseSMPTE(%q) failed: %v", test.smpte, err)
+			continue
+		}
+		if frames := parsed.Frames(test.n, test.d); frames != test.frames%(int64(test.n)*86400/int64(test.d)) {
+			t.Errorf("ParseSMPTE(%q) returned frame %d, want %d", test.smpte, frames, test.frames)
+		}
+	}
+
+	for _, invalid := range []string{"00:01:00;00", "24:00:00:00", "00:00:00:30", "00:00:00", "aa:00:00:00"} {
+		if _, err := ParseSMPTE(invalid, 30000, 1001); err == nil {
+			t.Errorf("ParseSMPTE(%q) did not fail", invalid)
+		}
+	}
+}
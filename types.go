@@ -1,6 +1,10 @@
 package gondi
 
-import "math"
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+)
 
 type VideoFrameV2 struct {
 	// The resolution of this frame.
@@ -132,6 +136,22 @@ const (
 
 	//Read the SDK documentation to understand the pros and cons of this format.
 	RecvColorFormatFastest RecvColorFormat = 100
+
+	//Receive the best quality format the source provides, for instance 16bit formats for sources sending them.
+	RecvColorFormatBest RecvColorFormat = 101
+)
+
+// Compressed passthrough formats. These are only supported by the NDI Advanced SDK, where video and audio frames
+// are received in their compressed form instead of being decoded. The standard SDK fails to create a receiver with them.
+const (
+	RecvColorFormatCompressed            RecvColorFormat = 300
+	RecvColorFormatCompressedV2          RecvColorFormat = 301
+	RecvColorFormatCompressedV3          RecvColorFormat = 302
+	RecvColorFormatCompressedV3WithAudio RecvColorFormat = 304
+	RecvColorFormatCompressedV4          RecvColorFormat = 303
+	RecvColorFormatCompressedV4WithAudio RecvColorFormat = 305
+	RecvColorFormatCompressedV5          RecvColorFormat = 307
+	RecvColorFormatCompressedV5WithAudio RecvColorFormat = 308
 )
 
 /* Borrowed from ndi-go/ndi.go */
@@ -155,6 +175,28 @@ type RecvPerformance struct {
 	MetadataFrames int64
 }
 
+type SendPerformance struct {
+	//The number of video frames sent
+	VideoFrames int64
+
+	//The number of audio frames sent
+	AudioFrames int64
+
+	//The number of metadata frames sent
+	MetadataFrames int64
+}
+
+type RecvQueue struct {
+	//The number of video frames waiting to be captured
+	VideoFrames int32
+
+	//The number of audio frames waiting to be captured
+	AudioFrames int32
+
+	//The number of metadata frames waiting to be captured
+	MetadataFrames int32
+}
+
 type MetadataFrame struct {
 	// The length of the string in UTF8 characters. This includes the NULL terminating character.
 	// If this is 0, then the length is assume to be the length of a null terminated string.
@@ -187,16 +229,19 @@ type routingCreateSettings struct {
 	groups *byte
 }
 
+// Receiver creation settings. Note that the zero values are not the SDK defaults, the zero value of ColorFormat is
+// gondi.RecvColorFormatBGRXBGRA, of Bandwidth is gondi.RecvBandwidthLowest, and AllowVideoFields is false.
+// NewRecvInstanceWithOptions starts from the SDK defaults instead.
 type NewRecvInstanceSettings struct {
-	// Source to connect to
+	// Source to connect to. If this is nil, the receiver is not connected until Connect is called.
 	SourceToConnectTo *Source
 
-	// Your preferred colorspace, default is gondi.RecvColorFormatUYVYBGRA
+	// Your preferred colorspace, the SDK default is gondi.RecvColorFormatUYVYBGRA
 	ColorFormat RecvColorFormat
 
 	// The bandwidth setting that you wish to use for this video source. Bandwidth
 	// controlled by changing both the compression level and the resolution of the source.
-	// Default value, and for full quality and all frame types: gondi.RecvBandwidthHighest
+	// SDK default value, and for full quality and all frame types: gondi.RecvBandwidthHighest
 	Bandwidth RecvBandwidth
 
 	// When this flag is FALSE, all video that you receive will be progressive. For sources
@@ -204,7 +249,7 @@ type NewRecvInstanceSettings struct {
 	// what the up-stream source was actually rendering. This is provided as a convenience to
 	// down-stream sources that do not wish to understand fielded video. There is almost no
 	// performance impact of using this function.
-	// Default is true
+	// SDK default is true
 	AllowVideoFields bool
 
 	// The name of the ndi receiver to create. This should be named the same way that you
@@ -257,28 +302,91 @@ type sendCreateSettings struct {
 	clockVideo, clockAudio bool
 }
 
+// Options for NewSendInstanceWithOptions
+type SendOptions struct {
+	// The name of the NDI source to create. The SDK prefixes it with the machine name, see SendInstance.SourceName.
+	Name string
+
+	// Comma separated list of groups this source should be part of. Empty means the default groups.
+	Groups string
+
+	// Clock video and audio, see NewSendInstance.
+	ClockVideo, ClockAudio bool
+
+	// Configuration JSON for this sender only, in the same format as the ndi-config.v1.json file.
+	// Empty means the global configuration is used. This is ignored by SDK versions without per-instance configuration.
+	Config string
+}
+
 // Sender instance struct
 type SendInstance struct {
 	ndiInstance    uintptr
 	createSettings *sendCreateSettings
+	strings        cAllocator
+	state          instanceState
+
+	workers workers
+
+	tallyMu       sync.Mutex
+	tally         TallyChange
+	tallyWatchers subscribers[TallyChange]
+
+	connectionsMu      sync.Mutex
+	connections        ConnectionChange
+	connectionWatchers subscribers[ConnectionChange]
+
+	metadata metadataRouter
+
+	// Serialises metadata sends with changes to the connection metadata
+	metadataMu sync.Mutex
+
+	connectionMetadataOnce sync.Once
+	connectionMetadata     *ConnectionMetadata
+
+	sentVideo, sentAudio, sentMetadata atomic.Int64
 }
 
 // Finder instance struct
 type FindInstance struct {
 	ndiInstance    uintptr
 	createSettings *findCreateSettings
+	strings        cAllocator
+	state          instanceState
+
+	// The SDK frees the list of sources on the next call, so calls and the copies they make are serialised
+	sourcesMu sync.Mutex
 }
 
 // Receiver instance struct
 type RecvInstance struct {
 	ndiInstance    uintptr
 	createSettings *recvCreateSettings
+	strings        cAllocator
+	state          instanceState
+
+	tallyMu sync.Mutex
+	tally   Tally
+
+	// Serialises metadata sends with changes to the connection metadata
+	metadataMu sync.Mutex
+
+	connectionMetadataOnce sync.Once
+	connectionMetadata     *ConnectionMetadata
 }
 
 // ROuting instance struct
 type RoutingInstance struct {
 	ndiInstance    uintptr
 	createSettings *routingCreateSettings
+	strings        cAllocator
+	state          instanceState
 	name           string
 	groups         string
+
+	workers workers
+
+	// The routed source, and the finder used to follow it
+	routeMu sync.Mutex
+	routed  Source
+	follow  *FindInstance
 }
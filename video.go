@@ -0,0 +1,74 @@
+package gondi
+
+import "unsafe"
+
+// Get the default line stride in bytes for a frame of this FourCC and horizontal resolution.
+// Returns 0 if the FourCC is not known.
+func (f FourCCType) LineStride(xres int32) int32 {
+	switch f {
+	case FourCCTypeUYVY, FourCCTypeUYVA:
+		return xres * 2
+	case FourCCTypeBGRA, FourCCTypeBGRX:
+		return xres * 4
+	default:
+		return 0
+	}
+}
+
+// Get the string representation of the FourCC, for instance "UYVY".
+func (f FourCCType) String() string {
+	return string(f[:])
+}
+
+// Get the size in bytes of the video data of this frame, based on its FourCC, resolution and line stride.
+// If LineStride is 0, the default stride for the FourCC is used. Returns 0 if the FourCC is not known.
+func (p *VideoFrameV2) DataSize() int {
+	stride := int(p.LineStride)
+	if stride == 0 {
+		stride = int(p.FourCC.LineStride(p.Xres))
+	}
+
+	size := stride * int(p.Yres)
+	if p.FourCC == FourCCTypeUYVA {
+		// The alpha plane follows the UYVY plane, with one byte per pixel
+		size += int(p.Xres) * int(p.Yres)
+	}
+
+	return size
+}
+
+// Get the video data as a byte slice of DataSize() bytes, or nil if the frame has no data.
+func (p *VideoFrameV2) GetData() []byte {
+	if p.Data == nil {
+		return nil
+	}
+	return unsafe.Slice(p.Data, p.DataSize())
+}
+
+// Fill the video data of the frame with black. Alpha is set to opaque for formats that have it.
+func (p *VideoFrameV2) FillBlack() {
+	data := p.GetData()
+
+	switch p.FourCC {
+	case FourCCTypeUYVY, FourCCTypeUYVA:
+		uyvy := len(data)
+		if p.FourCC == FourCCTypeUYVA {
+			uyvy -= int(p.Xres) * int(p.Yres)
+			for i := uyvy; i < len(data); i++ {
+				data[i] = 0xff
+			}
+		}
+		for i := 0; i+1 < uyvy; i += 2 {
+			data[i] = 0x80
+			data[i+1] = 0x10
+		}
+	case FourCCTypeBGRA, FourCCTypeBGRX:
+		for i := 0; i+3 < len(data); i += 4 {
+			data[i], data[i+1], data[i+2], data[i+3] = 0, 0, 0, 0xff
+		}
+	default:
+		for i := range data {
+			data[i] = 0
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package gondi
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Background goroutines polling an instance. They are stopped, and waited for, before the instance is destroyed,
+// so they never call into the SDK with a destroyed instance.
+type workers struct {
+	mu      sync.Mutex
+	running map[string]*worker
+	live    map[*worker]struct{}
+	stopped bool
+}
+
+type worker struct {
+	stop chan struct{}
+	done chan struct{}
+
+	// Set while the worker runs a callback of the user, which may close the instance from the worker itself
+	inCallback atomic.Bool
+}
+
+// Run fn in a new goroutine, unless a worker with the same name is running.
+// The stop channel is closed when the worker should return.
+func (w *workers) start(name string, fn func(stop <-chan struct{})) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stopped || w.running[name] != nil {
+		return
+	}
+	if w.running == nil {
+		w.running = make(map[string]*worker)
+		w.live = make(map[*worker]struct{})
+	}
+	wk := &worker{stop: make(chan struct{}), done: make(chan struct{})}
+	w.running[name] = wk
+	w.live[wk] = struct{}{}
+
+	go func() {
+		defer close(wk.done)
+		defer w.remove(name, wk)
+		fn(wk.stop)
+	}()
+}
+
+// Forget a worker that has returned.
+func (w *workers) remove(name string, wk *worker) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.running[name] == wk {
+		delete(w.running, name)
+	}
+	delete(w.live, wk)
+}
+
+// Returns true if a worker with the given name is running, and has not been stopped.
+func (w *workers) isRunning(name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return !w.stopped && w.running[name] != nil
+}
+
+// Ask a single worker to return, without waiting for it. A worker with the same name can be started right away,
+// and may run alongside the stopping one for a moment. A worker can also stop itself with this before returning,
+// so a start racing with it is not lost.
+func (w *workers) stop(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if wk := w.running[name]; wk != nil {
+		close(wk.stop)
+		delete(w.running, name)
+	}
+}
+
+// Run a callback of the user on the worker owning the stop channel. Shutting down from within the callback does not
+// wait for the worker running it, as it would never return.
+func (w *workers) callback(stop <-chan struct{}, fn func()) {
+	w.mu.Lock()
+	var running *worker
+	for wk := range w.live {
+		if wk.stop == stop {
+			running = wk
+			break
+		}
+	}
+	w.mu.Unlock()
+
+	if running != nil {
+		running.inCallback.Store(true)
+		defer running.inCallback.Store(false)
+	}
+	fn()
+}
+
+// Stop all workers and wait for them to return. No workers can be started after this.
+// Workers running a callback are not waited for, as the callback may be the one shutting down. They return as soon
+// as the callback does, and cannot call into the SDK anymore once the instance is closed.
+func (w *workers) shutdown() {
+	w.mu.Lock()
+	w.stopped = true
+	for _, wk := range w.running {
+		close(wk.stop)
+	}
+	w.running = nil
+	live := make([]*worker, 0, len(w.live))
+	for wk := range w.live {
+		live = append(live, wk)
+	}
+	w.mu.Unlock()
+
+	for _, wk := range live {
+		if !wk.inCallback.Load() {
+			<-wk.done
+		}
+	}
+}
+
+// Channels receiving the events published by a worker.
+type subscribers[T any] struct {
+	mu     sync.Mutex
+	chans  map[chan T]struct{}
+	done   chan struct{}
+	closed bool
+}
+
+// Add a subscriber channel with the given buffer size. The channel is closed when the context is done,
+// or when the subscribers are closed. If initial is not nil, it is delivered before any published value.
+func (s *subscribers[T]) add(ctx context.Context, size int, initial *T) <-chan T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan T, size)
+	if s.closed {
+		close(ch)
+		return ch
+	}
+	if s.chans == nil {
+		s.chans = make(map[chan T]struct{})
+		s.done = make(chan struct{})
+	}
+	s.chans[ch] = struct{}{}
+	if initial != nil {
+		ch <- *initial
+	}
+
+	done := s.done
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.chans[ch]; ok {
+			delete(s.chans, ch)
+			close(ch)
+		}
+	}()
+
+	return ch
+}
+
+// Returns true if there is at least one subscriber.
+func (s *subscribers[T]) any() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.chans) > 0
+}
+
+// Deliver a value to all subscribers. When a subscriber has a full buffer, its oldest value is dropped to make room,
+// so a slow subscriber never blocks the worker, and the last value it receives is always the latest one.
+func (s *subscribers[T]) publish(v T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.chans {
+		for {
+			select {
+			case ch <- v:
+			default:
+				// Full, drop the oldest value, unless the subscriber took one meanwhile
+				select {
+				case <-ch:
+				default:
+				}
+				continue
+			}
+			break
+		}
+	}
+}
+
+// Close all subscriber channels. Subscribers added after this get a closed channel.
+func (s *subscribers[T]) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	for ch := range s.chans {
+		close(ch)
+	}
+	s.chans = nil
+	if s.done != nil {
+		close(s.done)
+	}
+}
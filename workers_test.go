@@ -0,0 +1,100 @@
+package gondi
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSubscribers(t *testing.T) {
+	var subs subscribers[int]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	initial := 1
+	first := subs.add(ctx, 4, &initial)
+	second := subs.add(context.Background(), 4, nil)
+
+	subs.publish(2)
+	if v := <-first; v != 1 {
+		t.Errorf("first value is %d, want the initial value 1", v)
+	}
+	if v := <-first; v != 2 {
+		t.Errorf("second value is %d, want 2", v)
+	}
+
+	cancel()
+	if _, ok := <-first; ok {
+		t.Error("channel was not closed when the context was cancelled")
+	}
+
+	subs.close()
+	if v := <-second; v != 2 {
+		t.Errorf("value is %d, want 2", v)
+	}
+	if _, ok := <-second; ok {
+		t.Error("channel was not closed when the subscribers were closed")
+	}
+	if _, ok := <-subs.add(context.Background(), 1, nil); ok {
+		t.Error("channel added after close is not closed")
+	}
+}
+
+func TestSubscribersSlow(t *testing.T) {
+	var subs subscribers[int]
+	ch := subs.add(context.Background(), 2, nil)
+
+	// A subscriber that does not keep up misses the oldest values, and still ends with the latest one
+	for v := 1; v <= 5; v++ {
+		subs.publish(v)
+	}
+	if a, b := <-ch, <-ch; a != 4 || b != 5 {
+		t.Errorf("received %d, %d, want 4, 5", a, b)
+	}
+}
+
+func TestWorkersShutdown(t *testing.T) {
+	var w workers
+	started := make(chan struct{})
+	stopped := false
+
+	w.start("test", func(stop <-chan struct{}) {
+		close(started)
+		<-stop
+		stopped = true
+	})
+	w.start("test", func(stop <-chan struct{}) {
+		t.Error("a second worker with the same name was started")
+	})
+	<-started
+
+	w.shutdown()
+	if !stopped {
+		t.Error("shutdown returned before the worker stopped")
+	}
+	if w.isRunning("test") {
+		t.Error("worker is reported running after shutdown")
+	}
+}
+
+func TestWorkersStop(t *testing.T) {
+	var w workers
+	stopped := make(chan struct{})
+
+	w.start("test", func(stop <-chan struct{}) {
+		<-stop
+		close(stopped)
+	})
+	w.stop("test")
+	<-stopped
+	if w.isRunning("test") {
+		t.Error("worker is reported running after it was stopped")
+	}
+
+	// A stopped worker can be started again, and shutting down from a callback does not wait for itself
+	done := make(chan struct{})
+	w.start("test", func(stop <-chan struct{}) {
+		w.callback(stop, w.shutdown)
+		close(done)
+	})
+	<-done
+	w.shutdown()
+}